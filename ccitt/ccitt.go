@@ -0,0 +1,327 @@
+// CCITT fax decoder for PDF (Group 3 1D/2D and Group 4).
+package ccitt
+
+// Params are the parameters of the /CCITTFaxDecode filter.
+type Params struct {
+	K                int  // < 0: Group 4, 0: Group 3 1D, > 0: Group 3 mixed 1D/2D
+	Columns          int  // width of the image in pixels, 0 for 1728
+	Rows             int  // height of the image, 0 if unknown
+	EndOfBlock       bool // data ends with EOFB/RTC, decoding stops there
+	BlackIs1         bool // 1 bits are black pixels, else white ones
+	EncodedByteAlign bool // each line starts at a byte boundary
+}
+
+const (
+	_MODE_P = iota
+	_MODE_H
+	_MODE_V0
+	_MODE_VR1
+	_MODE_VR2
+	_MODE_VR3
+	_MODE_VL1
+	_MODE_VL2
+	_MODE_VL3
+)
+
+const _EOL = 1 // 000000000001
+
+// codes are stored with their length: length<<16 | code
+func key(code string) int {
+	r := 0
+	for _, c := range code {
+		r = r<<1 | int(c-'0')
+	}
+	return len(code)<<16 | r
+}
+
+var whiteTerm = [64]string{
+	"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+	"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+	"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+	"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+	"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+	"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+	"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+	"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+}
+
+// makeup codes for 64, 128, ... 1728
+var whiteMakeup = [27]string{
+	"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+	"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+	"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+	"010011010", "011000", "010011011",
+}
+
+var blackTerm = [64]string{
+	"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+	"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+	"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+	"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+	"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+	"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+	"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+	"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+}
+
+var blackMakeup = [27]string{
+	"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+	"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+	"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+	"0000001011011", "0000001100100", "0000001100101",
+}
+
+// makeup codes for 1792, 1856, ... 2560, for both colors
+var extMakeup = [13]string{
+	"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101",
+	"000000010110", "000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+}
+
+var modeCodes = map[string]int{
+	"0001": _MODE_P, "001": _MODE_H, "1": _MODE_V0,
+	"011": _MODE_VR1, "000011": _MODE_VR2, "0000011": _MODE_VR3,
+	"010": _MODE_VL1, "000010": _MODE_VL2, "0000010": _MODE_VL3,
+}
+
+var white, black, modes map[int]int
+
+func init() {
+	white = make(map[int]int)
+	black = make(map[int]int)
+	modes = make(map[int]int)
+	for i := range whiteTerm {
+		white[key(whiteTerm[i])] = i
+		black[key(blackTerm[i])] = i
+	}
+	for i := range whiteMakeup {
+		white[key(whiteMakeup[i])] = 64 * (i + 1)
+		black[key(blackMakeup[i])] = 64 * (i + 1)
+	}
+	for i := range extMakeup {
+		white[key(extMakeup[i])] = 1792 + 64*i
+		black[key(extMakeup[i])] = 1792 + 64*i
+	}
+	for c, m := range modeCodes {
+		modes[key(c)] = m
+	}
+}
+
+type bitsT struct {
+	s   []byte
+	pos int // position in bits
+}
+
+func (b *bitsT) peek(n int) int {
+	r := 0
+	for p := b.pos; p < b.pos+n; p++ {
+		r <<= 1
+		if p>>3 < len(b.s) {
+			r |= int(b.s[p>>3]>>uint(7-p&7)) & 1
+		}
+	}
+	return r
+}
+
+func (b *bitsT) skip(n int) { b.pos += n }
+
+func (b *bitsT) align() { b.pos = (b.pos + 7) &^ 7 }
+
+func (b *bitsT) eof() bool { return b.pos >= len(b.s)*8 }
+
+// code() reads a code of up to max bits from table t, -1 if there is none.
+func (b *bitsT) code(t map[int]int, max int) int {
+	for n := 1; n <= max; n++ {
+		if v, ok := t[n<<16|b.peek(n)]; ok {
+			b.skip(n)
+			return v
+		}
+	}
+	return -1
+}
+
+// run() reads a run length of the given color (0 white, 1 black).
+func (b *bitsT) run(color int) int {
+	t := white
+	if color == 1 {
+		t = black
+	}
+	r := 0
+	for {
+		n := b.code(t, 13)
+		if n < 0 {
+			return -1
+		}
+		r += n
+		if n < 64 {
+			return r
+		}
+	}
+}
+
+type decoder struct {
+	bits    *bitsT
+	columns int
+	ref     []int // changing elements of the reference line
+	cur     []int // changing elements of the current line
+}
+
+// line1D() decodes a line of run lengths.
+func (d *decoder) line1D() bool {
+	d.cur = d.cur[:0]
+	for a0, color := 0, 0; a0 < d.columns; color ^= 1 {
+		n := d.bits.run(color)
+		if n < 0 {
+			return false
+		}
+		if a0 += n; a0 > d.columns {
+			a0 = d.columns
+		}
+		d.cur = append(d.cur, a0)
+	}
+	return true
+}
+
+// line2D() decodes a line coded relative to the reference line.
+func (d *decoder) line2D() bool {
+	d.cur = d.cur[:0]
+	for a0, color := -1, 0; a0 < d.columns; {
+		// b1 is the first changing element of the reference line right
+		// of a0 that changes to the other color, b2 the next one
+		i := 0
+		for i < len(d.ref) && (d.ref[i] <= a0 || i&1 != color) {
+			i++
+		}
+		b1, b2 := d.columns, d.columns
+		if i < len(d.ref) {
+			b1 = d.ref[i]
+		}
+		if i+1 < len(d.ref) {
+			b2 = d.ref[i+1]
+		}
+
+		var a1 int
+		switch d.bits.code(modes, 7) {
+		case _MODE_P:
+			a0 = b2
+			continue
+		case _MODE_H:
+			start := a0
+			if start < 0 {
+				start = 0
+			}
+			r1 := d.bits.run(color)
+			r2 := d.bits.run(color ^ 1)
+			if r1 < 0 || r2 < 0 || (r1 == 0 && r2 == 0) {
+				return false
+			}
+			a1 = start + r1
+			a2 := a1 + r2
+			if a2 > d.columns {
+				a2 = d.columns
+			}
+			if a1 > d.columns {
+				a1 = d.columns
+			}
+			d.cur = append(d.cur, a1, a2)
+			a0 = a2
+			continue
+		case _MODE_V0:
+			a1 = b1
+		case _MODE_VR1:
+			a1 = b1 + 1
+		case _MODE_VR2:
+			a1 = b1 + 2
+		case _MODE_VR3:
+			a1 = b1 + 3
+		case _MODE_VL1:
+			a1 = b1 - 1
+		case _MODE_VL2:
+			a1 = b1 - 2
+		case _MODE_VL3:
+			a1 = b1 - 3
+		default:
+			return false
+		}
+		if a1 <= a0 {
+			return false
+		}
+		if a1 > d.columns {
+			a1 = d.columns
+		}
+		d.cur = append(d.cur, a1)
+		a0 = a1
+		color ^= 1
+	}
+	return true
+}
+
+// pixels() appends the current line to out, 1 bit per pixel.
+func (d *decoder) pixels(out []byte, blackIs1 bool) []byte {
+	line := make([]byte, (d.columns+7)/8)
+	x, color := 0, 0
+	for _, e := range append(d.cur, d.columns) {
+		if e > d.columns {
+			e = d.columns
+		}
+		if (color == 0) != blackIs1 {
+			for ; x < e; x++ {
+				line[x>>3] |= 0x80 >> uint(x&7)
+			}
+		}
+		x = e
+		color ^= 1
+	}
+	return append(out, line...)
+}
+
+// Decode() decodes CCITT fax data. Lines that can't be decoded end the
+// data, the lines decoded so far are returned.
+func Decode(data []byte, p Params) []byte {
+	d := &decoder{bits: &bitsT{s: data}, columns: p.Columns}
+	if d.columns <= 0 {
+		d.columns = 1728
+	}
+	d.ref = []int{d.columns}
+
+	var out []byte
+	for rows := 0; p.Rows <= 0 || rows < p.Rows; rows++ {
+		if p.EncodedByteAlign {
+			d.bits.align()
+		}
+		// skip fill bits and EOL, two EOLs are the end of the data
+		for !d.bits.eof() && d.bits.peek(12) == 0 {
+			d.bits.skip(1)
+		}
+		if d.bits.peek(12) == _EOL {
+			d.bits.skip(12)
+			if p.EndOfBlock && d.bits.peek(12) == _EOL {
+				break
+			}
+		}
+		if d.bits.eof() {
+			break
+		}
+
+		ok := false
+		switch {
+		case p.K < 0:
+			ok = d.line2D()
+		case p.K == 0:
+			ok = d.line1D()
+		default:
+			tag := d.bits.peek(1)
+			d.bits.skip(1)
+			if tag == 1 {
+				ok = d.line1D()
+			} else {
+				ok = d.line2D()
+			}
+		}
+		if !ok {
+			break
+		}
+		out = d.pixels(out, p.BlackIs1)
+		d.ref, d.cur = d.cur, d.ref
+	}
+	return out
+}
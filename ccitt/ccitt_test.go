@@ -0,0 +1,79 @@
+package ccitt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// bits() packs a string of '0' and '1', spaces ignored, MSB first.
+func bits(s string) []byte {
+	s = strings.Replace(s, " ", "", -1)
+	out := make([]byte, (len(s)+7)/8)
+	for i, c := range s {
+		if c == '1' {
+			out[i>>3] |= 0x80 >> uint(i&7)
+		}
+	}
+	return out
+}
+
+// The lines are 8 pixels: 3 white, 2 black, 3 white (E7 with white as 1),
+// all black and all white.
+func TestDecode(t *testing.T) {
+	const eol = "000000000001 "
+	tests := []struct {
+		name string
+		data string
+		p    Params
+		want []byte
+	}{
+		{"1D", "1000 11 1000  00110101 000101", Params{Columns: 8, Rows: 2}, []byte{0xe7, 0x00}},
+		{"1D BlackIs1", "1000 11 1000  00110101 000101", Params{Columns: 8, Rows: 2, BlackIs1: true}, []byte{0x18, 0xff}},
+		{"1D EOL", eol + "1000 11 1000 " + eol + "00110101 000101 " + eol + eol,
+			Params{Columns: 8, EndOfBlock: true}, []byte{0xe7, 0x00}},
+		{"1D aligned", "1000 11 1000 000000  00110101 000101 00",
+			Params{Columns: 8, Rows: 2, EncodedByteAlign: true}, []byte{0xe7, 0x00}},
+		// H W3 B2, V0 / V0 V0 V0 / P V0
+		{"G4", "001 1000 11 1  1 1 1  0001 1", Params{K: -1, Columns: 8, Rows: 3}, []byte{0xe7, 0xe7, 0xff}},
+		{"G4 EOFB", "001 1000 11 1  1 1 1 " + eol + eol, Params{K: -1, Columns: 8, EndOfBlock: true}, []byte{0xe7, 0xe7}},
+		{"2D", eol + "1 1000 11 1000 " + eol + "0 1 1 1 " + eol + "0 0001 1",
+			Params{K: 2, Columns: 8, Rows: 3}, []byte{0xe7, 0xe7, 0xff}},
+	}
+	for _, tt := range tests {
+		if got := Decode(bits(tt.data), tt.p); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: Decode() = % x, want % x", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestDecodeFiles decodes strips written by libtiff, 1 is black.
+func TestDecodeFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Params
+	}{
+		{"g31d", Params{K: 0, Columns: 300, Rows: 120, BlackIs1: true}},
+		{"g32d", Params{K: 1, Columns: 300, Rows: 120, BlackIs1: true}},
+		{"g32dfill", Params{K: 1, Columns: 301, Rows: 97, BlackIs1: true}}, // fill bits before the EOLs
+		{"g4", Params{K: -1, Columns: 300, Rows: 120, BlackIs1: true}},
+	}
+	for _, tt := range tests {
+		data, err := ioutil.ReadFile("testdata/" + tt.name + ".raw")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := ioutil.ReadFile("testdata/" + tt.name + ".px")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Decode(data, tt.p); !bytes.Equal(got, want) {
+			t.Errorf("%s: Decode() = %d bytes, want %d", tt.name, len(got), len(want))
+		}
+		tt.p.Rows = 0
+		if got := Decode(data, tt.p); !bytes.Equal(got, want) {
+			t.Errorf("%s without Rows: Decode() = %d bytes, want %d", tt.name, len(got), len(want))
+		}
+	}
+}
@@ -9,6 +9,10 @@
 package lzw
 
 import (
+	"bufio"
+	"errors"
+	"io"
+
 	"github.com/raff/pdfreader/crush"
 )
 
@@ -108,3 +112,123 @@ func Decode(s []byte, early bool) []byte {
 	r := make([]byte, CalculateLength(s, early)+1)
 	return r[0:DecodeToSlice(s, r, early)]
 }
+
+// reader is a streaming LZW decoder.
+type reader struct {
+	r      io.ByteReader
+	early  bool
+	bits   uint32 // bit buffer
+	nbits  int    // number of bits in the buffer
+	bc     int    // code length
+	next   int    // next code to define
+	prev   int    // previous code, -1 after a reset
+	prefix [_LZW_DICSIZE]int
+	suffix [_LZW_DICSIZE]byte
+	length [_LZW_DICSIZE]int
+	out    []byte // decoded, not yet returned
+	buf    []byte
+	err    error
+}
+
+// NewReader() returns a reader that decodes the LZW data read from r.
+func NewReader(r io.Reader, early bool) io.Reader {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	z := &reader{r: br, early: early}
+	for i := 0; i <= 255; i++ {
+		z.prefix[i] = -1
+		z.suffix[i] = byte(i)
+		z.length[i] = 1
+	}
+	z.reset()
+	return z
+}
+
+func (z *reader) reset() {
+	z.bc = _LZW_STARTBITS
+	z.next = _LZW_STARTUTOK
+	z.prev = -1
+}
+
+func (z *reader) code() (int, error) {
+	for z.nbits < z.bc {
+		c, err := z.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		z.bits = z.bits<<8 | uint32(c)
+		z.nbits += 8
+	}
+	z.nbits -= z.bc
+	return int(z.bits>>uint(z.nbits)) & (1<<uint(z.bc) - 1), nil
+}
+
+// str() returns the string of code c.
+func (z *reader) str(c int) []byte {
+	n := z.length[c]
+	if cap(z.buf) < n {
+		z.buf = make([]byte, n)
+	}
+	s := z.buf[:n]
+	for i := n - 1; i >= 0; i-- {
+		s[i] = z.suffix[c]
+		c = z.prefix[c]
+	}
+	return s
+}
+
+func (z *reader) Read(p []byte) (int, error) {
+	for len(z.out) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		c, err := z.code()
+		if err != nil || c == _LZW_EOD {
+			z.err = io.EOF
+			continue
+		}
+		if c == _LZW_RESET {
+			z.reset()
+			continue
+		}
+
+		var s []byte
+		switch {
+		case c < _LZW_RESET || (c >= _LZW_STARTUTOK && c < z.next):
+			s = z.str(c)
+		case c == z.next && z.prev >= 0:
+			// the code being defined: previous string and its first byte
+			s = append(z.str(z.prev), 0)
+			s[len(s)-1] = s[0]
+		default:
+			z.err = errors.New("lzw: invalid code")
+			continue
+		}
+
+		if z.prev >= 0 && z.next < _LZW_DICSIZE {
+			z.prefix[z.next] = z.prev
+			z.suffix[z.next] = s[0]
+			z.length[z.next] = z.length[z.prev] + 1
+			z.next++
+			cmp := z.next
+			if z.early {
+				cmp++
+			}
+			switch {
+			case cmp >= 2048:
+				z.bc = 12
+			case cmp >= 1024:
+				z.bc = 11
+			case cmp >= 512:
+				z.bc = 10
+			}
+		}
+		z.prev = c
+		z.out = s
+	}
+	n := copy(p, z.out)
+	z.out = z.out[n:]
+	return n, nil
+}
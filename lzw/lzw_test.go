@@ -0,0 +1,124 @@
+package lzw
+
+import (
+	"bytes"
+	"compress/lzw"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+// encode() is a plain LZW encoder for the tests, with the code length
+// switching one code early if early is set. It never fills the table.
+func encode(data []byte, early bool) []byte {
+	var out bytes.Buffer
+	var bits uint32
+	nbits, width := 0, _LZW_STARTBITS
+	put := func(c int) {
+		bits = bits<<uint(width) | uint32(c)
+		for nbits += width; nbits >= 8; nbits -= 8 {
+			out.WriteByte(byte(bits >> uint(nbits-8)))
+		}
+	}
+	hi := _LZW_EOD
+	added := func() {
+		hi++
+		e := 0
+		if early {
+			e = 1
+		}
+		switch hi + e {
+		case 512, 1024, 2048:
+			width++
+		}
+	}
+
+	dict := make(map[string]int)
+	put(_LZW_RESET)
+	s := ""
+	for i := range data {
+		c := string(data[i : i+1])
+		if _, ok := dict[s+c]; ok || s == "" {
+			s += c
+			continue
+		}
+		put(code(dict, s))
+		dict[s+c] = hi + 1
+		added()
+		s = c
+	}
+	if s != "" {
+		put(code(dict, s))
+		added()
+	}
+	put(_LZW_EOD)
+	if nbits > 0 {
+		out.WriteByte(byte(bits << uint(8-nbits)))
+	}
+	return out.Bytes()
+}
+
+func code(dict map[string]int, s string) int {
+	if len(s) == 1 {
+		return int(s[0])
+	}
+	return dict[s]
+}
+
+func random(n int, seed int64) []byte {
+	r := rand.New(rand.NewSource(seed))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(r.Intn(256))
+	}
+	return b
+}
+
+func decodeBoth(t *testing.T, name string, data []byte, early bool, want []byte) {
+	if got := Decode(data, early); !bytes.Equal(got, want) {
+		t.Errorf("%s: Decode() = %d bytes, want %d", name, len(got), len(want))
+	}
+	got, err := ioutil.ReadAll(NewReader(bytes.NewReader(data), early))
+	if err != nil {
+		t.Errorf("%s: NewReader(): %v", name, err)
+	} else if !bytes.Equal(got, want) {
+		t.Errorf("%s: NewReader() = %d bytes, want %d", name, len(got), len(want))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		early bool
+		want  []byte
+	}{
+		// the example of the PDF reference, 7.4.4.2
+		{"spec", []byte{0x80, 0x0b, 0x60, 0x50, 0x22, 0x0c, 0x0c, 0x85, 0x01}, true, []byte("-----A---B")},
+		{"empty", []byte{0x80, 0x40, 0x40}, true, []byte{}},
+		{"early 1", encode([]byte("TOBEORNOTTOBEORTOBEORNOT"), true), true, []byte("TOBEORNOTTOBEORTOBEORNOT")},
+		{"early 0", encode([]byte("TOBEORNOTTOBEORTOBEORNOT"), false), false, []byte("TOBEORNOTTOBEORTOBEORNOT")},
+		{"widths early 1", encode(random(3000, 1), true), true, random(3000, 1)},
+		{"widths early 0", encode(random(3000, 1), false), false, random(3000, 1)},
+		{"runs early 1", encode(bytes.Repeat([]byte("ab"), 20000), true), true, bytes.Repeat([]byte("ab"), 20000)},
+	}
+	for _, tt := range tests {
+		decodeBoth(t, tt.name, tt.data, tt.early, tt.want)
+	}
+}
+
+// TestCompress decodes the output of compress/lzw, which is LZW with
+// EarlyChange 0. The larger inputs fill the table and reset it.
+func TestCompress(t *testing.T) {
+	for _, n := range []int{1, 100, 3000, 20000, 100000} {
+		want := random(n, int64(n))
+		var b bytes.Buffer
+		w := lzw.NewWriter(&b, lzw.MSB, 8)
+		w.Write(want)
+		w.Close()
+		if n == 3000 && !bytes.Equal(b.Bytes(), encode(want, false)) {
+			t.Errorf("encode() differs from compress/lzw")
+		}
+		decodeBoth(t, "compress/lzw", b.Bytes(), false, want)
+	}
+}
@@ -0,0 +1,199 @@
+package pdfread
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// annotation flags in Annotation.Flags (/F of the annotation).
+const (
+	AnnotInvisible      = 1 << 0
+	AnnotHidden         = 1 << 1
+	AnnotPrint          = 1 << 2
+	AnnotNoZoom         = 1 << 3
+	AnnotNoRotate       = 1 << 4
+	AnnotNoView         = 1 << 5
+	AnnotReadOnly       = 1 << 6
+	AnnotLocked         = 1 << 7
+	AnnotToggleNoView   = 1 << 8
+	AnnotLockedContents = 1 << 9
+)
+
+// Annotation is an annotation of a page. The fields after Dict are only
+// set for the subtypes given in their comments; everything else is in
+// Dict.
+type Annotation struct {
+	Subtype  string      // /Link, /Text, /FreeText, /Highlight, /Stamp, /Widget, ...
+	Ref      []byte      // reference of the annotation, nil if it's a direct object
+	Rect     Rectangle   // /Rect
+	Flags    int         // /F - see the Annot* flags
+	Color    []float64   // /C - 1 (gray), 3 (RGB) or 4 (CMYK) components, nil if none
+	Contents string      // /Contents - text of the annotation, or its alternate description
+	Name     string      // /NM - unique name on the page
+	Modified time.Time   // /M, zero if missing or not a date
+	Dict     DictionaryT // the annotation dictionary
+
+	// markup annotations (comments)
+	Author    string    // /T
+	Subject   string    // /Subj
+	Created   time.Time // /CreationDate
+	Popup     []byte    // /Popup - reference of the popup annotation
+	InReplyTo []byte    // /IRT - reference of the annotation this one replies to
+	ReplyType string    // /RT - /R (reply) or /Group, /R if missing with InReplyTo
+
+	Parent          []byte       // Popup: reference of the annotation the popup belongs to
+	Open            bool         // Text, Popup: shown open
+	Icon            string       // /Name - Text, Stamp and FileAttachment icon
+	State           string       // Text: /State of a review - Accepted, Rejected, Completed, ...
+	StateModel      string       // Text: /StateModel - Marked or Review
+	Dest            *Destination // Link: /Dest, or the target of a /GoTo action
+	Action          *Action      // Link, Widget: /A, nil if none
+	QuadPoints      []float64    // Link, Highlight, Underline, Squiggly, StrikeOut: /QuadPoints
+	InkList         [][]float64  // Ink: the paths of /InkList
+	File            string       // FileAttachment: file name of /FS
+	FileStream      []byte       // FileAttachment: reference of the embedded file stream
+	Field           string       // Widget: fully qualified name of the field
+	AppearanceState string       // Widget: /AS - appearance state, as the on-state of check boxes
+}
+
+// markupAnnots are the annotation subtypes with author, dates, popup and
+// replies.
+var markupAnnots = map[string]bool{
+	"/Text": true, "/FreeText": true, "/Line": true, "/Square": true,
+	"/Circle": true, "/Polygon": true, "/PolyLine": true, "/Highlight": true,
+	"/Underline": true, "/Squiggly": true, "/StrikeOut": true, "/Stamp": true,
+	"/Caret": true, "/Ink": true, "/FileAttachment": true, "/Sound": true,
+	"/Redact": true,
+}
+
+// p.Annotations() returns the annotations of the page (/Annots) in their
+// order on the page. Entries that aren't dictionaries are skipped.
+func (p *Page) Annotations() []Annotation {
+	pd := p.pd
+	var annots []Annotation
+	for _, r := range pd.Arr(p.Dict["/Annots"]) {
+		d := pd.Dic(r)
+		if d == nil {
+			continue
+		}
+		a := Annotation{
+			Subtype:  string(pd.Obj(d["/Subtype"])),
+			Ref:      normRef(r),
+			Flags:    pd.Num(d["/F"]),
+			Color:    pd.floats(d["/C"]),
+			Contents: pd.Text(d["/Contents"]),
+			Name:     pd.Text(d["/NM"]),
+			Dict:     d,
+		}
+		a.Rect, _ = pd.rectangle(d["/Rect"])
+		a.Modified, _ = ParseDate(pd.Text(d["/M"]))
+
+		if markupAnnots[a.Subtype] {
+			a.Author = pd.Text(d["/T"])
+			a.Subject = pd.Text(d["/Subj"])
+			a.Created, _ = ParseDate(pd.Text(d["/CreationDate"]))
+			a.Popup = normRef(d["/Popup"])
+			if a.InReplyTo = normRef(d["/IRT"]); a.InReplyTo != nil {
+				a.ReplyType = "/R"
+				if rt := string(pd.Obj(d["/RT"])); rt != "" {
+					a.ReplyType = rt
+				}
+			}
+		}
+
+		switch a.Subtype {
+		case "/Popup":
+			a.Parent = normRef(d["/Parent"])
+			a.Open = string(pd.Obj(d["/Open"])) == "true"
+		case "/Text":
+			a.Open = string(pd.Obj(d["/Open"])) == "true"
+			a.Icon = string(pd.Obj(d["/Name"]))
+			a.State = pd.Text(d["/State"])
+			a.StateModel = pd.Text(d["/StateModel"])
+		case "/Stamp", "/FileAttachment":
+			a.Icon = string(pd.Obj(d["/Name"]))
+			if a.Subtype == "/FileAttachment" {
+				a.File = pd.fileSpec(d["/FS"])
+				ef := pd.Dic(pd.Dic(d["/FS"])["/EF"])
+				for _, k := range []string{"/UF", "/F"} {
+					if f, ok := ef[k]; ok {
+						a.FileStream = normRef(f)
+						break
+					}
+				}
+			}
+		case "/Link":
+			if dest, ok := d["/Dest"]; ok {
+				a.Dest = pd.destination(dest)
+			} else if act, ok := d["/A"]; ok {
+				a.Action = pd.action(act)
+				if a.Action != nil && a.Action.Type == "/GoTo" {
+					a.Dest = a.Action.Dest
+				}
+			}
+			a.QuadPoints = pd.floats(d["/QuadPoints"])
+		case "/Highlight", "/Underline", "/Squiggly", "/StrikeOut":
+			a.QuadPoints = pd.floats(d["/QuadPoints"])
+		case "/Ink":
+			for _, path := range pd.Arr(d["/InkList"]) {
+				a.InkList = append(a.InkList, pd.floats(path))
+			}
+		case "/Widget":
+			if act, ok := d["/A"]; ok {
+				a.Action = pd.action(act)
+			}
+			a.Field = pd.fieldName(d)
+			a.AppearanceState = string(pd.Obj(d["/AS"]))
+		}
+		annots = append(annots, a)
+	}
+	return annots
+}
+
+// pd.floats() reads an array of numbers, nil if it's not one. Entries
+// that aren't numbers are 0.
+func (pd *PdfReaderT) floats(reference []byte) []float64 {
+	a := pd.Arr(reference)
+	if len(a) == 0 {
+		return nil
+	}
+	r := make([]float64, len(a))
+	for i, v := range a {
+		r[i] = pd.Float(v)
+	}
+	return r
+}
+
+// pd.fieldName() returns the fully qualified name of a form field: the
+// partial names (/T) of the field and its ancestors, joined by dots.
+// Fields without a partial name don't add to it.
+func (pd *PdfReaderT) fieldName(d DictionaryT) string {
+	var names []string
+	done := make(map[int]bool)
+	for depth := 0; d != nil && depth <= MAX_TREE_DEPTH; depth++ {
+		if t, ok := d["/T"]; ok {
+			names = append(names, pd.Text(t))
+		}
+		n, _ := refNums(d["/Parent"])
+		if n < 0 || done[n] {
+			break
+		}
+		done[n] = true
+		d = pd.Dic(d["/Parent"])
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, ".")
+}
+
+// normRef() returns a reference in the form "o g R", so references can be
+// compared. It's nil if r is not a reference.
+func normRef(r []byte) []byte {
+	o, g := refNums(r)
+	if o < 0 {
+		return nil
+	}
+	return []byte(fmt.Sprintf("%d %d R", o, g))
+}
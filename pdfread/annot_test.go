@@ -0,0 +1,82 @@
+package pdfread
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestAnnotations reads the annotations of the first page of annot.pdf:
+// links, comments with a popup and replies, ink, a file attachment, a
+// widget, a direct annotation and a reference to a missing object.
+func TestAnnotations(t *testing.T) {
+	pd, err := Open("testdata/annot.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	annots := pd.Page(0).Annotations()
+	subtypes := []string{"/Link", "/Link", "/Link", "/Link", "/Link", "/Text", "/Popup", "/Text",
+		"/Highlight", "/Ink", "/FileAttachment", "/Widget", "/Square", "/Stamp"}
+	if len(annots) != len(subtypes) {
+		t.Fatalf("%d annotations", len(annots))
+	}
+	for i, a := range annots {
+		if a.Subtype != subtypes[i] {
+			t.Errorf("annotation %d: %s, want %s", i, a.Subtype, subtypes[i])
+		}
+	}
+
+	a := annots[0]
+	if string(a.Ref) != "10 0 R" || a.Rect != (Rectangle{100, 700, 200, 720}) || a.Flags != AnnotPrint ||
+		a.Action == nil || a.Action.URI != "http://example.com/" || len(a.QuadPoints) != 8 {
+		t.Errorf("URI link: %+v", a)
+	}
+	if d := annots[1].Dest; d == nil || d.Page != 1 || d.Name != "target" || d.View != "/Fit" {
+		t.Errorf("named link: %+v", d)
+	}
+	if act := annots[2].Action; act == nil || act.File != "other.pdf" || act.Dest == nil || act.Dest.Page != 2 {
+		t.Errorf("GoToR link: %+v", act)
+	}
+	if act := annots[3].Action; act == nil || act.Type != "/Launch" || act.File != "é.txt " {
+		t.Errorf("Launch link: %+v", act)
+	}
+	if act := annots[4].Action; act == nil || act.Name != "/NextPage" {
+		t.Errorf("Named link: %+v", act)
+	}
+
+	c := annots[5]
+	if c.Contents != "Please fix clause 3" || c.Author != "Alice" || c.Subject != "Comment" || c.Name != "c1" ||
+		!c.Modified.Equal(time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)) || !c.Created.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		!reflect.DeepEqual(c.Color, []float64{1, 1, 0}) || c.Icon != "/Comment" || !c.Open || string(c.Popup) != "16 0 R" {
+		t.Errorf("comment: %+v", c)
+	}
+	if p := annots[6]; string(p.Parent) != "15 0 R" || p.Open || p.Author != "" {
+		t.Errorf("popup: %+v", p)
+	}
+	if r := annots[7]; string(r.InReplyTo) != "15 0 R" || r.ReplyType != "/R" || r.State != "Completed" || r.StateModel != "Review" {
+		t.Errorf("reply: %+v", r)
+	}
+	if h := annots[8]; h.ReplyType != "/Group" || h.Author != "Carol" || len(h.QuadPoints) != 8 {
+		t.Errorf("highlight: %+v", h)
+	}
+	if ink := annots[9].InkList; !reflect.DeepEqual(ink, [][]float64{{1, 2, 3, 4}, {5, 6, 7, 8, 9, 10}}) {
+		t.Errorf("ink: %v", ink)
+	}
+	if f := annots[10]; f.File != "data.csv" || string(f.FileStream) != "30 0 R" || f.Icon != "/Paperclip" {
+		t.Errorf("file attachment: %+v", f)
+	}
+	if w := annots[11]; w.Field != "form.agree" || w.AppearanceState != "/Yes" || w.Action == nil || w.Action.Name != "/Print" || w.Author != "" {
+		t.Errorf("widget: %+v", w)
+	}
+	if s := annots[12]; s.Ref != nil || s.Author != "direct" {
+		t.Errorf("direct annotation: %+v", s)
+	}
+	if s := annots[13]; s.Icon != "/Approved" || s.Author != "Eve" {
+		t.Errorf("stamp: %+v", s)
+	}
+
+	if a := pd.Page(1).Annotations(); len(a) != 0 {
+		t.Errorf("page 1: %d annotations", len(a))
+	}
+}
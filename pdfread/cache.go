@@ -0,0 +1,119 @@
+package pdfread
+
+import (
+	"container/list"
+	"io"
+	"strconv"
+	"sync"
+)
+
+const (
+	_CACHE_SIZE  = 4096 // default number of resolved objects to keep
+	_OBJSTM_SIZE = 16   // default number of decoded object streams to keep
+	_BLOCK_SIZE  = 4096 // size of the blocks of blockCacheT
+	_BLOCK_COUNT = 256  // number of blocks to keep
+)
+
+// cacheT is a cache with a size limit that drops the least recently used
+// entries. It's safe for concurrent use; a nil cache caches nothing.
+type cacheT struct {
+	mu  sync.Mutex
+	max int // max number of entries, < 0 for no limit
+	l   *list.List
+	m   map[string]*list.Element
+}
+
+type cacheEntryT struct {
+	key string
+	val interface{}
+}
+
+// newCache() creates a cache for max entries, def if max is 0.
+func newCache(max, def int) *cacheT {
+	if max == 0 {
+		max = def
+	}
+	return &cacheT{max: max, l: list.New(), m: make(map[string]*list.Element)}
+}
+
+func (c *cacheT) get(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+	c.l.MoveToFront(e)
+	return e.Value.(*cacheEntryT).val, true
+}
+
+func (c *cacheT) put(key string, val interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.m[key]; ok {
+		e.Value.(*cacheEntryT).val = val
+		c.l.MoveToFront(e)
+		return
+	}
+	c.m[key] = c.l.PushFront(&cacheEntryT{key, val})
+	for c.max >= 0 && c.l.Len() > c.max {
+		e := c.l.Back()
+		delete(c.m, e.Value.(*cacheEntryT).key)
+		c.l.Remove(e)
+	}
+}
+
+// blockCacheT is an io.ReaderAt that keeps the recently read blocks of
+// another one. The readers of pd.at() share it, so the objects they parse
+// are read from the file once.
+type blockCacheT struct {
+	ra   io.ReaderAt
+	size int64
+	c    *cacheT
+}
+
+func newBlockCache(ra io.ReaderAt, size int64) *blockCacheT {
+	return &blockCacheT{ra: ra, size: size, c: newCache(_BLOCK_COUNT, _BLOCK_COUNT)}
+}
+
+func (b *blockCacheT) ReadAt(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos < 0 || pos >= b.size {
+			return n, io.EOF
+		}
+		blk, berr := b.block(pos / _BLOCK_SIZE)
+		if berr != nil {
+			return n, berr
+		}
+		n += copy(p[n:], blk[pos%_BLOCK_SIZE:])
+	}
+	return n, nil
+}
+
+// b.block() returns block k, from the cache or read.
+func (b *blockCacheT) block(k int64) ([]byte, error) {
+	key := strconv.FormatInt(k, 10)
+	if v, ok := b.c.get(key); ok {
+		return v.([]byte), nil
+	}
+	n := b.size - k*_BLOCK_SIZE
+	if n > _BLOCK_SIZE {
+		n = _BLOCK_SIZE
+	}
+	buf := make([]byte, n)
+	if m, err := b.ra.ReadAt(buf, k*_BLOCK_SIZE); m < len(buf) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	b.c.put(key, buf)
+	return buf, nil
+}
@@ -0,0 +1,95 @@
+package pdfread
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	c := newCache(2, 10)
+	c.put("a", 1)
+	c.put("b", 2)
+	c.get("a")
+	c.put("c", 3) // drops b, the least recently used
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.get(key); ok != want {
+			t.Errorf("%s cached: %v", key, ok)
+		}
+	}
+	if c := newCache(0, 10); c.max != 10 {
+		t.Errorf("default size %d", c.max)
+	}
+
+	c = newCache(-1, 10)
+	for i := 0; i < 100; i++ {
+		c.put(fmt.Sprint(i), i)
+	}
+	if c.l.Len() != 100 {
+		t.Errorf("unlimited cache with %d entries", c.l.Len())
+	}
+
+	var nc *cacheT
+	nc.put("a", 1)
+	if _, ok := nc.get("a"); ok {
+		t.Errorf("nil cache returned a value")
+	}
+}
+
+func TestBlockCache(t *testing.T) {
+	data := make([]byte, 3*_BLOCK_SIZE+100)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	r := &readerAt{Reader: bytes.NewReader(data)}
+	b := newBlockCache(r, int64(len(data)))
+	for _, off := range []int64{0, _BLOCK_SIZE - 10, 3 * _BLOCK_SIZE, 10} {
+		p := make([]byte, 50)
+		if n, err := b.ReadAt(p, off); n != 50 || err != nil || !bytes.Equal(p, data[off:off+50]) {
+			t.Errorf("ReadAt(%d) = %d, %v", off, n, err)
+		}
+	}
+	if r.reads != 3 { // blocks 0, 1 and 3
+		t.Errorf("%d reads", r.reads)
+	}
+	p := make([]byte, 200)
+	if n, err := b.ReadAt(p, int64(len(data))-100); n != 100 || err == nil {
+		t.Errorf("ReadAt() past the end = %d, %v", n, err)
+	}
+}
+
+// TestConcurrent reads the same objects and pages from several goroutines,
+// with caches too small to hold them; run it with -race.
+func TestConcurrent(t *testing.T) {
+	for _, file := range []string{"hybrid.pdf", "r4aespw.pdf", "inc.pdf"} {
+		pd, err := OpenOptions("testdata/"+file, &Options{Password: "user", CacheSize: 2, ObjStmCache: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make(map[int]string)
+		for o := range pd.Xref {
+			want[o] = string(pd.Obj([]byte(fmt.Sprintf("%d 0 R", o))))
+		}
+		pages := len(pd.Pages())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for o, w := range want {
+					if got := string(pd.Obj([]byte(fmt.Sprintf("%d 0 R", o)))); got != w {
+						t.Errorf("%s: object %d = %q, want %q", file, o, got, w)
+					}
+				}
+				if n := len(pd.Pages()); n != pages {
+					t.Errorf("%s: %d pages, want %d", file, n, pages)
+				}
+				pd.Warnings()
+			}()
+		}
+		wg.Wait()
+		pd.Close()
+	}
+}
@@ -0,0 +1,553 @@
+package pdfread
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rc4"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/raff/pdfreader/ps"
+	"github.com/raff/pdfreader/util"
+)
+
+// errors of the security handler.
+var (
+	ErrBadPassword        = errors.New("incorrect password")
+	ErrUnsupportedEncrypt = errors.New("unsupported encryption")
+	ErrBadPerms           = errors.New("/Perms do not match /P")
+)
+
+// permission flags in pd.Permissions (/P of the /Encrypt dictionary).
+const (
+	PermPrint     = 1 << 2
+	PermModify    = 1 << 3
+	PermCopy      = 1 << 4
+	PermAnnotate  = 1 << 5
+	PermFillForms = 1 << 8
+	PermExtract   = 1 << 9
+	PermAssemble  = 1 << 10
+	PermPrintHigh = 1 << 11
+	PermAll       = -1
+)
+
+// crypt methods (/CFM of a crypt filter).
+const (
+	_CRYPT_NONE     = "/None"
+	_CRYPT_RC4      = "/V2"
+	_CRYPT_AESV2    = "/AESV2"
+	_CRYPT_AESV3    = "/AESV3"
+	_CRYPT_IDENTITY = "/Identity"
+)
+
+var passwordPad = []byte{
+	0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41,
+	0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+	0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80,
+	0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+}
+
+// cryptT is the state of the standard security handler.
+type cryptT struct {
+	key             []byte            // file encryption key
+	stmf, strf, eff string            // crypt methods for streams, strings and embedded files
+	cf              map[string]string // crypt methods of the named crypt filters
+	encref          int               // object number of the /Encrypt dictionary
+	metadata        bool              // /EncryptMetadata
+}
+
+func padPassword(pw []byte) []byte {
+	r := make([]byte, 32)
+	n := copy(r, pw)
+	copy(r[n:], passwordPad)
+	return r
+}
+
+func rc4Crypt(key, data []byte) []byte {
+	c, err := rc4.NewCipher(key)
+	if err != nil {
+		return data
+	}
+	r := make([]byte, len(data))
+	c.XORKeyStream(r, data)
+	return r
+}
+
+// rc4Rounds() applies the 19 extra RC4 rounds of revision 3 and later
+// (the first round with i == 0 is the plain key).
+func rc4Rounds(key, data []byte, down bool) []byte {
+	k := make([]byte, len(key))
+	for n := 0; n < 20; n++ {
+		i := n
+		if down {
+			i = 19 - n
+		}
+		for j := range key {
+			k[j] = key[j] ^ byte(i)
+		}
+		data = rc4Crypt(k, data)
+	}
+	return data
+}
+
+// aesDecryptBlocks() decrypts data without IV (all zero) and padding.
+func aesDecryptBlocks(key, data []byte, ecb bool) []byte {
+	b, err := aes.NewCipher(key)
+	if err != nil || len(data)%aes.BlockSize != 0 {
+		return nil
+	}
+	r := make([]byte, len(data))
+	if ecb {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			b.Decrypt(r[i:], data[i:])
+		}
+	} else {
+		cipher.NewCBCDecrypter(b, make([]byte, aes.BlockSize)).CryptBlocks(r, data)
+	}
+	return r
+}
+
+// hash6() is the password hash of revision 5 and 6 (Algorithm 2.B).
+func hash6(rev int, pw, salt, udata []byte) []byte {
+	h := sha256.New()
+	h.Write(pw)
+	h.Write(salt)
+	h.Write(udata)
+	k := h.Sum(nil)
+	if rev == 5 {
+		return k
+	}
+	for round := 0; ; round++ {
+		k1 := make([]byte, 0, 64*(len(pw)+len(k)+len(udata)))
+		for i := 0; i < 64; i++ {
+			k1 = append(k1, pw...)
+			k1 = append(k1, k...)
+			k1 = append(k1, udata...)
+		}
+		b, _ := aes.NewCipher(k[:16])
+		e := make([]byte, len(k1))
+		cipher.NewCBCEncrypter(b, k[16:32]).CryptBlocks(e, k1)
+		sum := 0
+		for _, c := range e[:16] {
+			sum += int(c)
+		}
+		var hh hash.Hash
+		switch sum % 3 {
+		case 0:
+			hh = sha256.New()
+		case 1:
+			hh = sha512.New384()
+		case 2:
+			hh = sha512.New()
+		}
+		hh.Write(e)
+		k = hh.Sum(nil)
+		if round >= 63 && int(e[len(e)-1]) <= round+1-32 {
+			break
+		}
+	}
+	return k[:32]
+}
+
+func aesDecrypt(key, data []byte) []byte {
+	if len(data) < 2*aes.BlockSize {
+		return []byte{}
+	}
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return []byte{}
+	}
+	iv := data[:aes.BlockSize]
+	data = data[aes.BlockSize:]
+	data = data[:len(data)-len(data)%aes.BlockSize]
+	r := make([]byte, len(data))
+	cipher.NewCBCDecrypter(b, iv).CryptBlocks(r, data)
+	if p := int(r[len(r)-1]); p > 0 && p <= aes.BlockSize && p <= len(r) {
+		if bytes.Count(r[len(r)-p:], r[len(r)-1:]) == p {
+			r = r[:len(r)-p]
+		}
+	}
+	return r
+}
+
+// newCrypt() sets up the standard security handler from the /Encrypt
+// dictionary of the trailer and checks the password against it.
+func newCrypt(pd *PdfReaderT, password string) (*cryptT, error) {
+	enc := pd.Dic(pd.Trailer["/Encrypt"])
+	if enc == nil || string(enc["/Filter"]) != "/Standard" {
+		return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+
+	c := &cryptT{encref: -1, metadata: string(enc["/EncryptMetadata"]) != "false",
+		stmf: _CRYPT_RC4, strf: _CRYPT_RC4, cf: map[string]string{_CRYPT_IDENTITY: _CRYPT_NONE}}
+	if r := pd.Trailer["/Encrypt"]; len(r) > 0 && r[len(r)-1] == 'R' {
+		c.encref = num(r)
+	}
+
+	v := pd.Num(enc["/V"])
+	rev := pd.Num(enc["/R"])
+	n := pd.Num(enc["/Length"]) / 8
+
+	switch {
+	case v == 1 || rev == 2:
+		n = 5
+	case v == 2 || v == 3:
+		if n == 0 {
+			n = 5
+		}
+	case v == 4 || v == 5:
+		for name, f := range pd.Dic(enc["/CF"]) {
+			if m := string(pd.Dic(f)["/CFM"]); m != "" {
+				c.cf[name] = m
+			} else {
+				c.cf[name] = _CRYPT_NONE
+			}
+		}
+		// "" for names that aren't in /CF, they are rejected below
+		method := func(f []byte) string {
+			if f = pd.Obj(f); len(f) == 0 {
+				return _CRYPT_NONE
+			}
+			return c.cf[string(f)]
+		}
+		c.stmf = method(enc["/StmF"])
+		c.strf = method(enc["/StrF"])
+		n = 16
+		if v == 5 {
+			n = 32
+		}
+	default:
+		return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+	c.eff = c.stmf
+	if f, ok := enc["/EFF"]; ok {
+		c.eff = c.cf[string(pd.Obj(f))]
+	}
+	if c.stmf == "" || c.strf == "" || c.eff == "" {
+		util.Log("unknown crypt filter", string(enc["/StmF"]), string(enc["/StrF"]), string(enc["/EFF"]))
+		return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+
+	if rev < 2 || rev > 6 || (rev >= 5) != (v == 5) || n < 5 || (n > 16 && v != 5) {
+		return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+	for _, m := range c.cf {
+		switch m {
+		case _CRYPT_NONE, _CRYPT_RC4, _CRYPT_AESV2:
+		case _CRYPT_AESV3:
+			if v != 5 {
+				return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+			}
+		default:
+			util.Log("unsupported crypt filter", m)
+			return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+		}
+	}
+
+	o := ps.String(pd.Obj(enc["/O"]))
+	u := ps.String(pd.Obj(enc["/U"]))
+	p := uint32(pd.Num(enc["/P"]))
+	if v == 5 {
+		if err := c.auth6(pd, enc, rev, []byte(password), o, u, p); err != nil {
+			return nil, err
+		}
+		pd.Encrypted = true
+		pd.Permissions = int32(p)
+		return c, nil
+	}
+	if len(o) < 32 || len(u) < 32 {
+		return nil, parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+	o, u = o[:32], u[:32]
+	var id []byte
+	if ids := pd.Arr(pd.Trailer["/ID"]); len(ids) > 0 {
+		id = ps.String(pd.Obj(ids[0]))
+	}
+
+	// Algorithm 2: compute the file key for a (padded) user password.
+	fileKey := func(pw []byte) []byte {
+		h := md5.New()
+		h.Write(padPassword(pw))
+		h.Write(o)
+		h.Write([]byte{byte(p), byte(p >> 8), byte(p >> 16), byte(p >> 24)})
+		h.Write(id)
+		if rev >= 4 && !c.metadata {
+			h.Write([]byte{0xff, 0xff, 0xff, 0xff})
+		}
+		k := h.Sum(nil)
+		if rev >= 3 {
+			for i := 0; i < 50; i++ {
+				s := md5.Sum(k[:n])
+				k = s[:]
+			}
+		}
+		return k[:n]
+	}
+
+	// Algorithms 4 and 5: check a user password.
+	user := func(pw []byte) []byte {
+		k := fileKey(pw)
+		if rev == 2 {
+			if bytes.Equal(rc4Crypt(k, passwordPad), u) {
+				return k
+			}
+			return nil
+		}
+		h := md5.New()
+		h.Write(passwordPad)
+		h.Write(id)
+		if bytes.Equal(rc4Rounds(k, h.Sum(nil), false), u[:16]) {
+			return k
+		}
+		return nil
+	}
+
+	// Algorithm 7: check an owner password by recovering the user password.
+	owner := func(pw []byte) []byte {
+		k := md5.Sum(padPassword(pw))
+		if rev >= 3 {
+			for i := 0; i < 50; i++ {
+				k = md5.Sum(k[:])
+			}
+		}
+		if rev == 2 {
+			return user(rc4Crypt(k[:n], o))
+		}
+		return user(rc4Rounds(k[:n], o, true))
+	}
+
+	if c.key = user([]byte(password)); c.key == nil {
+		if c.key = owner([]byte(password)); c.key == nil {
+			return nil, parseError(pd.File, -1, ErrBadPassword)
+		}
+	}
+
+	pd.Encrypted = true
+	pd.Permissions = int32(p)
+	return c, nil
+}
+
+// c.auth6() checks the password of revision 5 and 6 (Algorithms 2.A and 13)
+// and sets the file key.
+func (c *cryptT) auth6(pd *PdfReaderT, enc DictionaryT, rev int, pw, o, u []byte, p uint32) error {
+	if len(o) < 48 || len(u) < 48 {
+		return parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+	o, u = o[:48], u[:48]
+	if len(pw) > 127 {
+		pw = pw[:127]
+	}
+
+	var ke []byte
+	switch {
+	case bytes.Equal(hash6(rev, pw, o[32:40], u), o[:32]):
+		ke = aesDecryptBlocks(hash6(rev, pw, o[40:48], u), ps.String(pd.Obj(enc["/OE"])), false)
+	case bytes.Equal(hash6(rev, pw, u[32:40], nil), u[:32]):
+		ke = aesDecryptBlocks(hash6(rev, pw, u[40:48], nil), ps.String(pd.Obj(enc["/UE"])), false)
+	default:
+		return parseError(pd.File, -1, ErrBadPassword)
+	}
+	if len(ke) != 32 {
+		return parseError(pd.File, -1, ErrUnsupportedEncrypt)
+	}
+	c.key = ke
+
+	perms := aesDecryptBlocks(c.key, ps.String(pd.Obj(enc["/Perms"])), true)
+	if rev == 6 && (len(perms) < 16 || string(perms[9:12]) != "adb" ||
+		uint32(perms[0])|uint32(perms[1])<<8|uint32(perms[2])<<16|uint32(perms[3])<<24 != p) {
+		return parseError(pd.File, -1, ErrBadPerms)
+	}
+	return nil
+}
+
+// c.objectKey() returns the key for the strings and streams of object o,
+// generation g.
+func (c *cryptT) objectKey(method string, o, g int) []byte {
+	if method == _CRYPT_AESV3 {
+		return c.key
+	}
+	h := md5.New()
+	h.Write(c.key)
+	h.Write([]byte{byte(o), byte(o >> 8), byte(o >> 16), byte(g), byte(g >> 8)})
+	if method == _CRYPT_AESV2 {
+		h.Write([]byte("sAlT"))
+	}
+	return h.Sum(nil)[:min(len(c.key)+5, 16)]
+}
+
+// c.decrypt() decrypts data of object o, generation g with a crypt method.
+func (c *cryptT) decrypt(method string, o, g int, data []byte) []byte {
+	if method == _CRYPT_NONE || o == c.encref {
+		return data
+	}
+	k := c.objectKey(method, o, g)
+	if method == _CRYPT_RC4 {
+		return rc4Crypt(k, data)
+	}
+	return aesDecrypt(k, data)
+}
+
+// c.decryptReader() is c.decrypt() for data read from r.
+func (c *cryptT) decryptReader(method string, o, g int, r io.Reader) io.Reader {
+	if method == _CRYPT_NONE || o == c.encref {
+		return r
+	}
+	k := c.objectKey(method, o, g)
+	if method == _CRYPT_RC4 {
+		s, err := rc4.NewCipher(k)
+		if err != nil {
+			return r
+		}
+		return cipher.StreamReader{S: s, R: r}
+	}
+	return &aesReader{r: r, key: k}
+}
+
+// aesReader decrypts AES-CBC data read from r: the IV, the encrypted
+// blocks and the padding in the last block.
+type aesReader struct {
+	r    io.Reader
+	key  []byte
+	mode cipher.BlockMode
+	last []byte // last decrypted block, kept back for the padding
+	buf  []byte // decrypted data not yet returned
+	err  error
+}
+
+func (a *aesReader) Read(p []byte) (int, error) {
+	for len(a.buf) == 0 {
+		if a.err != nil {
+			return 0, a.err
+		}
+		if a.mode == nil {
+			iv := make([]byte, aes.BlockSize)
+			b, err := aes.NewCipher(a.key)
+			if err != nil {
+				a.err = err
+				continue
+			}
+			if _, err := io.ReadFull(a.r, iv); err != nil {
+				a.err = io.EOF
+				continue
+			}
+			a.mode = cipher.NewCBCDecrypter(b, iv)
+		}
+
+		blk := make([]byte, 64*aes.BlockSize)
+		n, err := io.ReadFull(a.r, blk)
+		if n -= n % aes.BlockSize; n > 0 {
+			a.mode.CryptBlocks(blk[:n], blk[:n])
+			data := append(a.last, blk[:n]...)
+			a.buf = data[:len(data)-aes.BlockSize]
+			a.last = data[len(data)-aes.BlockSize:]
+		}
+		if err != nil {
+			r := a.last
+			if len(r) > 0 {
+				if p := int(r[len(r)-1]); p > 0 && p <= aes.BlockSize &&
+					bytes.Count(r[len(r)-p:], r[len(r)-1:]) == p {
+					r = r[:len(r)-p]
+				}
+			}
+			a.buf = append(a.buf, r...)
+			a.last = nil
+			a.err = io.EOF
+		}
+	}
+	n := copy(p, a.buf)
+	a.buf = a.buf[n:]
+	return n, nil
+}
+
+// pd.streamCrypt() returns the crypt method for a stream. A /Crypt filter
+// in the stream's filter list selects a named crypt filter.
+func (pd *PdfReaderT) streamCrypt(dic DictionaryT) string {
+	c := pd.crypt
+	switch string(dic["/Type"]) {
+	case "/XRef":
+		return _CRYPT_NONE
+	case "/Metadata":
+		if !c.metadata {
+			return _CRYPT_NONE
+		}
+	}
+	if f := pd.streamObj(dic, dic["/Filter"]); len(f) > 0 {
+		if filter := ForcedArray(f); len(filter) > 0 && string(filter[0]) == "/Crypt" {
+			name := _CRYPT_IDENTITY
+			if d := pd.streamObj(dic, dic["/DecodeParms"]); len(d) > 0 {
+				if parms := ForcedArray(d); len(parms) > 0 {
+					if n, ok := Dictionary(pd.streamObj(dic, parms[0]))["/Name"]; ok {
+						name = string(n)
+					}
+				}
+			}
+			if m, ok := c.cf[name]; ok {
+				return m
+			}
+			util.Log("unknown crypt filter", name)
+			return _CRYPT_NONE
+		}
+	}
+	if string(dic["/Type"]) == "/EmbeddedFile" {
+		return c.eff
+	}
+	return c.stmf
+}
+
+// c.decryptStrings() decrypts all strings in the PDF data of object o,
+// generation g. The decrypted strings are written as hex strings, so the
+// result can be used like unencrypted PDF data.
+func (c *cryptT) decryptStrings(s []byte, o, g int) []byte {
+	if c.strf == _CRYPT_NONE || o == c.encref {
+		return s
+	}
+	r := make([]byte, 0, len(s))
+	str := func(b []byte) {
+		b = c.decrypt(c.strf, o, g, ps.String(b))
+		r = append(r, '<')
+		r = append(r, hex.EncodeToString(b)...)
+		r = append(r, '>')
+	}
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '(':
+			j := i + 1
+			for depth := 1; j < len(s) && depth > 0; j++ {
+				switch s[j] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case '\\':
+					j++
+				}
+			}
+			str(s[i:min(j, len(s))])
+			i = j
+		case s[i] == '<' && i+1 < len(s) && s[i+1] == '<':
+			r = append(r, "<<"...)
+			i += 2
+		case s[i] == '<':
+			j := bytes.IndexByte(s[i:], '>')
+			if j < 0 {
+				j = len(s) - i - 1
+			}
+			str(s[i : i+j+1])
+			i += j + 1
+		case s[i] == '%':
+			for i < len(s) && s[i] != '\r' && s[i] != '\n' {
+				i++
+			}
+		default:
+			r = append(r, s[i])
+			i++
+		}
+	}
+	return r
+}
@@ -0,0 +1,106 @@
+package pdfread
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/raff/pdfreader/ps"
+)
+
+// The files in testdata have the title "Hello (World)" and a page with the
+// text "Hello"; the owner password is "owner".
+func TestPasswords(t *testing.T) {
+	tests := []struct {
+		file     string
+		password string
+		err      error
+	}{
+		{"plain.pdf", "", nil},
+		{"r2.pdf", "", nil}, // RC4 40 bits
+		{"r2.pdf", "owner", nil},
+		{"r2.pdf", "user", ErrBadPassword},
+		{"r3pw.pdf", "user", nil}, // RC4 128 bits
+		{"r3pw.pdf", "owner", nil},
+		{"r3pw.pdf", "", ErrBadPassword},
+		{"r4rc4.pdf", "", nil},       // crypt filters, RC4
+		{"r4aespw.pdf", "user", nil}, // AESV2, /EncryptMetadata false
+		{"r4aespw.pdf", "owner", nil},
+		{"r4aespw.pdf", "User", ErrBadPassword},
+		{"r5.pdf", "", nil}, // AESV3, SHA-256
+		{"r5.pdf", "owner", nil},
+		{"r6pw.pdf", "usér", nil}, // AESV3, the hash of ISO 32000-2
+		{"r6pw.pdf", "owner", nil},
+		{"r6pw.pdf", "user", ErrBadPassword},
+		{"r6pw.pdf", "", ErrBadPassword},
+		{"r6pw.pdf", "usér ", ErrBadPassword},
+		{"r6pw.pdf", "ownerowner", ErrBadPassword},
+	}
+	for _, tt := range tests {
+		pd, err := OpenOptions("testdata/"+tt.file, &Options{Password: tt.password})
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s %q: err = %v, want %v", tt.file, tt.password, err, tt.err)
+			}
+			if pd != nil {
+				pd.Close()
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %v", tt.file, tt.password, err)
+			continue
+		}
+		if pd.Encrypted != (tt.file != "plain.pdf") {
+			t.Errorf("%s %q: Encrypted %v", tt.file, tt.password, pd.Encrypted)
+		}
+		if title := ps.String(pd.Obj(pd.Dic(pd.Trailer["/Info"])["/Title"])); string(title) != "Hello (World)" {
+			t.Errorf("%s %q: title %q", tt.file, tt.password, title)
+		}
+		_, data := pd.DecodedStream(pd.Dic(pd.Pages()[0])["/Contents"])
+		if !bytes.Contains(data, []byte("(Hello) Tj")) {
+			t.Errorf("%s %q: contents %q", tt.file, tt.password, data)
+		}
+		pd.Close()
+	}
+}
+
+func TestPadPassword(t *testing.T) {
+	tests := []struct {
+		pw   string
+		want []byte
+	}{
+		{"", passwordPad},
+		{"owner", append([]byte("owner"), passwordPad[:27]...)},
+		{"0123456789012345678901234567890123", []byte("01234567890123456789012345678901")},
+	}
+	for _, tt := range tests {
+		if got := padPassword([]byte(tt.pw)); !bytes.Equal(got, tt.want) {
+			t.Errorf("padPassword(%q) = % x, want % x", tt.pw, got, tt.want)
+		}
+	}
+}
+
+func TestCryptFilterNames(t *testing.T) {
+	tests := []struct {
+		file      string
+		old, repl string // of the same length
+		err       error
+	}{
+		{"r4aespw.pdf", "/StmF /StdCF", "/StmF /StdCX", ErrUnsupportedEncrypt},
+		{"r4aespw.pdf", "/StrF /StdCF", "/StrF /StdCX", ErrUnsupportedEncrypt},
+		{"r5.pdf", "/StmF /StdCF", "/StmF /StdCX", ErrUnsupportedEncrypt},
+		{"r4aespw.pdf", "/StrF /StdCF", "/EFF  /StdCX", ErrUnsupportedEncrypt},
+		{"r4aespw.pdf", "/StrF /StdCF", "/EFF  /StdCF", nil},
+	}
+	for _, tt := range tests {
+		data := bytes.Replace(readFile(t, tt.file), []byte(tt.old), []byte(tt.repl), 1)
+		pd, err := NewReaderOptions(bytes.NewReader(data), int64(len(data)), tt.file, &Options{Password: "user"})
+		if tt.err == nil && err != nil || tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s %s: err = %v, want %v", tt.file, tt.repl, err, tt.err)
+		}
+		if pd != nil {
+			pd.Close()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package pdfread
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errors returned by the Open functions and by the error-returning
+// variants of the page, outline and stream accessors. They are usually wrapped in
+// a *ParseError, so use errors.Is() to check for them.
+var (
+	ErrNotPDF            = errors.New("not a PDF file")
+	ErrNoStartxref       = errors.New("startxref not found")
+	ErrBadXref           = errors.New("bad xref table")
+	ErrNoTrailer         = errors.New("no trailer dictionary")
+	ErrBadPageTree       = errors.New("bad page tree")
+	ErrBadOutlines       = errors.New("bad outlines")
+	ErrNoRevision        = errors.New("no such revision")
+	ErrBadHints          = errors.New("bad hint tables")
+	ErrNotStream         = errors.New("not a stream")
+	ErrUnsupportedFilter = errors.New("unsupported filter")
+	ErrExternalStream    = errors.New("can't open external stream")
+)
+
+// ParseError reports where parsing of a PDF file failed.
+type ParseError struct {
+	File   string // name of the file
+	Offset int64  // position in the file, -1 if unknown
+	Err    error  // one of the Err* values above
+}
+
+func (e *ParseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s: %v at offset %d", e.File, e.Err, e.Offset)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func parseError(fn string, offset int64, err error) error {
+	return &ParseError{File: fn, Offset: offset, Err: err}
+}
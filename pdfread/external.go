@@ -0,0 +1,54 @@
+package pdfread
+
+import (
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/raff/pdfreader/ps"
+)
+
+// pd.StreamFile() returns the name of the file holding the data of an
+// external stream (/F in the stream dictionary), "" if the data is in the
+// PDF file.
+func (pd *PdfReaderT) StreamFile(reference []byte) string {
+	f, ok := pd.Dic(reference)["/F"]
+	if !ok {
+		return ""
+	}
+	return pd.fileSpec(f)
+}
+
+// pd.fileSpec() returns the file name of a file specification: a string or
+// a dictionary with /UF, /F, /Unix or /DOS.
+func (pd *PdfReaderT) fileSpec(spec []byte) string {
+	s := pd.Obj(spec)
+	if d := Dictionary(s); d != nil {
+		s = nil
+		for _, k := range []string{"/UF", "/F", "/Unix", "/DOS"} {
+			if v, ok := d[k]; ok {
+				s = pd.Obj(v)
+				break
+			}
+		}
+	}
+	if len(s) == 0 || (s[0] != '(' && s[0] != '<') {
+		return ""
+	}
+	return DecodeText(ps.String(s))
+}
+
+// pd.openFile() opens the file of an external stream in Options.FS, or in
+// the directory of the PDF file with Options.ExternalFiles. Names must be
+// relative and stay inside that directory: absolute names and names
+// with ".." are rejected, and with Options.ExternalFiles symbolic links
+// can't lead out of it.
+func (pd *PdfReaderT) openFile(name string) (io.ReadCloser, error) {
+	if pd.files == nil {
+		return nil, fs.ErrPermission
+	}
+	if name = path.Clean(name); !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return pd.files.Open(name)
+}
@@ -0,0 +1,116 @@
+package pdfread
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// streamText reads stream o of pd with pd.StreamReader().
+func streamText(pd *PdfReaderT, o int) (string, error) {
+	_, r, err := pd.StreamReader([]byte(fmt.Sprintf("%d 0 R", o)))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	var b bytes.Buffer
+	_, err = b.ReadFrom(r)
+	return b.String(), err
+}
+
+// TestExternalFiles reads ext/ext.pdf, whose streams 3 to 6 are in the
+// files sub/data.bin (/FFilter /FlateDecode), plain.txt (/FFilter
+// [/ASCIIHexDecode]), sub/dätä.txt (a /UF file specification) and
+// missing.bin; stream 7 is in the PDF file.
+func TestExternalFiles(t *testing.T) {
+	pd, err := OpenOptions("testdata/ext/ext.pdf", &Options{ExternalFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		o          int
+		file, want string
+	}{
+		{3, "sub/data.bin", strings.Repeat("external flate data ", 10)},
+		{4, "plain.txt", "Hello external"},
+		{5, "sub/dätä.txt", "unicode name"},
+		{7, "", "inpdf"}, // /Length 4 is recovered
+	}
+	for _, tt := range tests {
+		if f := pd.StreamFile([]byte(fmt.Sprintf("%d 0 R", tt.o))); f != tt.file {
+			t.Errorf("stream %d: file %q, want %q", tt.o, f, tt.file)
+		}
+		if s, err := streamText(pd, tt.o); err != nil || s != tt.want {
+			t.Errorf("stream %d: %q, %v; want %q", tt.o, s, err, tt.want)
+		}
+	}
+	if _, err := streamText(pd, 6); !errors.Is(err, ErrExternalStream) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: err = %v", err)
+	}
+	pd.Close()
+
+	// external files are disabled by default
+	pd, err = Open("testdata/ext/ext.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := streamText(pd, 4); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("without Options.ExternalFiles: err = %v, want %v", err, fs.ErrPermission)
+	}
+	if s, err := streamText(pd, 7); err != nil || s != "inpdf" {
+		t.Errorf("stream 7 without Options.ExternalFiles: %q, %v", s, err)
+	}
+	pd.Close()
+
+	files := fstest.MapFS{"plain.txt": {Data: []byte("6f6b>")}}
+	data := readFile(t, "ext/ext.pdf")
+	pd, err = NewReaderOptions(bytes.NewReader(data), int64(len(data)), "ext", &Options{FS: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := streamText(pd, 4); err != nil || s != "ok" {
+		t.Errorf("Options.FS: %q, %v", s, err)
+	}
+	if _, err := streamText(pd, 3); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Options.FS: err = %v, want %v", err, fs.ErrNotExist)
+	}
+	pd.Close()
+}
+
+// TestExternalNames checks that external streams can't be read from
+// outside the directory of the PDF file, by name or by a symbolic link.
+func TestExternalNames(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	ext := readFile(t, "ext/ext.pdf")
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "plain.txt")); err != nil {
+		t.Skip(err)
+	}
+	// of the length of sub/data.bin, so the xref stays right
+	for _, name := range []string{"../secret///", "/etc/passwd/", "sub/../../se"} {
+		data := bytes.Replace(ext, []byte("(sub/data.bin)"), []byte("("+name+")"), 1)
+		fn := filepath.Join(dir, "ext.pdf")
+		if err := os.WriteFile(fn, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		pd, err := OpenOptions(fn, &Options{ExternalFiles: true})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if s, err := streamText(pd, 3); err == nil {
+			t.Errorf("%s: read %q", name, s)
+		}
+		if s, err := streamText(pd, 4); err == nil {
+			t.Errorf("link out of the directory: read %q", s)
+		}
+		pd.Close()
+	}
+}
@@ -0,0 +1,452 @@
+package pdfread
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"io"
+	"io/ioutil"
+
+	"github.com/raff/pdfreader/ccitt"
+	"github.com/raff/pdfreader/lzw"
+	"github.com/raff/pdfreader/util"
+)
+
+// image codecs, the data is returned as it is
+var imageFilters = map[string]bool{
+	"/DCTDecode":   true,
+	"/JPXDecode":   true,
+	"/JBIG2Decode": true,
+}
+
+// decodeParms() returns the /DecodeParms of each filter, nil for none.
+func decodeParms(dic DictionaryT, filter [][]byte) []DictionaryT {
+	r := make([]DictionaryT, len(filter))
+	d := dic["/DecodeParms"]
+	if len(d) == 0 {
+		return r
+	}
+	if d[0] == '[' {
+		// one entry per filter, null for the defaults
+		for i, p := range Array(d) {
+			if i < len(r) {
+				r[i] = Dictionary(p)
+			}
+		}
+		return r
+	}
+	// a single dictionary belongs to a single filter
+	if len(filter) != 1 {
+		util.Log("/DecodeParms is no array for", len(filter), "filters")
+		return r
+	}
+	r[0] = Dictionary(d)
+	return r
+}
+
+// pd.filterDic() returns a copy of a stream dictionary with /Filter and
+// /DecodeParms (and their array elements) resolved, as decodeStream()
+// doesn't resolve references. For external streams they are taken from
+// /FFilter and /FDecodeParms.
+func (pd *PdfReaderT) filterDic(dic DictionaryT) DictionaryT {
+	r := make(DictionaryT, len(dic))
+	for k, v := range dic {
+		r[k] = v
+	}
+	_, external := dic["/F"]
+	for _, k := range []string{"/Filter", "/DecodeParms"} {
+		v, ok := dic[k]
+		if external {
+			delete(r, k)
+			v, ok = dic["/F"+k[1:]]
+		}
+		if !ok {
+			continue
+		}
+		v = pd.streamObj(dic, v)
+		if a := Array(v); a != nil {
+			b := []byte{'['}
+			for i := range a {
+				if i > 0 {
+					b = append(b, ' ')
+				}
+				b = append(b, pd.streamObj(dic, a[i])...)
+			}
+			v = append(b, ']')
+		}
+		r[k] = v
+	}
+	return r
+}
+
+// decodeReader() chains the filters of a stream to r, up to an image
+// codec. It returns the reader and the codec ("" if all filters are
+// applied).
+func decodeReader(dic DictionaryT, r io.Reader) (io.Reader, string, error) {
+	f, ok := dic["/Filter"]
+	if !ok {
+		return r, "", nil
+	}
+	filter := ForcedArray(f)
+	parms := decodeParms(dic, filter)
+
+	for ff := range filter {
+		deco := parms[ff]
+		if imageFilters[string(filter[ff])] {
+			return r, string(filter[ff]), nil
+		}
+		switch string(filter[ff]) {
+		case "/FlateDecode":
+			z, err := zlib.NewReader(r)
+			if err != nil {
+				return nil, "", err
+			}
+			if r, err = predictorReader(z, deco); err != nil {
+				return nil, "", err
+			}
+		case "/LZWDecode":
+			early := true
+			if s, ok := deco["/EarlyChange"]; ok {
+				early = num(s) == 1
+			}
+			var err error
+			if r, err = predictorReader(lzw.NewReader(r, early), deco); err != nil {
+				return nil, "", err
+			}
+		case "/ASCII85Decode":
+			r = ascii85.NewDecoder(&eodReader{r: bufio.NewReader(r), eod: '~'})
+		case "/ASCIIHexDecode":
+			r = &hexReader{r: bufio.NewReader(r)}
+		case "/RunLengthDecode":
+			r = &runLengthReader{r: bufio.NewReader(r)}
+		case "/CCITTFaxDecode":
+			// the decoder needs all the data
+			data, err := ioutil.ReadAll(r)
+			if err != nil {
+				return nil, "", err
+			}
+			r = bytes.NewReader(ccitt.Decode(data, ccitt.Params{
+				K:                num(deco["/K"]),
+				Columns:          numdef(deco["/Columns"], 1728),
+				Rows:             num(deco["/Rows"]),
+				EndOfBlock:       string(deco["/EndOfBlock"]) != "false",
+				BlackIs1:         string(deco["/BlackIs1"]) == "true",
+				EncodedByteAlign: string(deco["/EncodedByteAlign"]) == "true",
+			}))
+		case "/Crypt":
+			// decryption is done by pd.Stream()
+		default:
+			util.Log("Unsupported filter", string(filter[ff]))
+			return nil, "", ErrUnsupportedFilter
+		}
+
+	}
+	return r, "", nil
+}
+
+// decodeStream() applies the filters of a stream, up to an image codec.
+// It returns the data and the codec ("" if all filters were applied).
+func decodeStream(dic DictionaryT, data []byte) ([]byte, string) {
+	if _, ok := dic["/Filter"]; !ok {
+		return data, ""
+	}
+	r, filter, err := decodeReader(dic, bytes.NewReader(data))
+	if err != nil {
+		return []byte{}, ""
+	}
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		util.Log("decodeStream", err)
+	}
+	return data, filter
+}
+
+// predictorReader() undoes the /Predictor of /FlateDecode and /LZWDecode.
+func predictorReader(r io.Reader, deco DictionaryT) (io.Reader, error) {
+	pred := numdef(deco["/Predictor"], 1)
+	colors := numdef(deco["/Colors"], 1)
+	columns := numdef(deco["/Columns"], 1)
+	bitspercomponent := numdef(deco["/BitsPerComponent"], 8)
+
+	if pred == 1 {
+		// no predictor
+		return r, nil
+	}
+	switch bitspercomponent {
+	case 1, 2, 4, 8, 16:
+	default:
+		util.Log("Unsupported bitspercomponent", bitspercomponent)
+		return nil, ErrUnsupportedFilter
+	}
+	// colors is checked first, colors*bitspercomponent is at most 512
+	if colors < 1 || colors > MAX_PRED_COLORS || columns < 1 || columns > MAX_PRED_ROW*8/(colors*bitspercomponent) {
+		util.Log("Bad predictor parameters", colors, columns)
+		return nil, ErrUnsupportedFilter
+	}
+
+	switch {
+	case pred == 2:
+		return newTIFFReader(r, colors, columns, bitspercomponent), nil
+	case pred >= 10 && pred <= 15:
+		return newPNGReader(r, colors, columns, bitspercomponent), nil
+	}
+	util.Log("Unsupported predictor", pred)
+	return nil, ErrUnsupportedFilter
+}
+
+// eodReader reads up to an end of data marker.
+type eodReader struct {
+	r   *bufio.Reader
+	eod byte
+}
+
+func (e *eodReader) Read(p []byte) (int, error) {
+	if e.r == nil {
+		return 0, io.EOF
+	}
+	n, err := e.r.Read(p)
+	if i := bytes.IndexByte(p[:n], e.eod); i >= 0 {
+		e.r = nil
+		return i, nil
+	}
+	return n, err
+}
+
+// hexReader decodes /ASCIIHexDecode data. White-space is skipped, '>' is
+// the end of the data.
+type hexReader struct {
+	r   *bufio.Reader
+	eod bool
+}
+
+// h.digit() returns the value of the next hex digit, -1 at the end.
+func (h *hexReader) digit() int {
+	for !h.eod {
+		c, err := h.r.ReadByte()
+		switch {
+		case err != nil || c == '>':
+			h.eod = true
+		case c >= '0' && c <= '9':
+			return int(c - '0')
+		case c >= 'a' && c <= 'f':
+			return int(c-'a') + 10
+		case c >= 'A' && c <= 'F':
+			return int(c-'A') + 10
+		}
+	}
+	return -1
+}
+
+func (h *hexReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		hi := h.digit()
+		if hi < 0 {
+			break
+		}
+		lo := h.digit()
+		if lo < 0 {
+			// a missing last digit is 0
+			lo = 0
+		}
+		p[n] = byte(hi<<4 | lo)
+		n++
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// runLengthReader decodes /RunLengthDecode data.
+type runLengthReader struct {
+	r   *bufio.Reader
+	buf []byte
+	eod bool
+}
+
+func (rl *runLengthReader) Read(p []byte) (int, error) {
+	for len(rl.buf) == 0 {
+		if rl.eod {
+			return 0, io.EOF
+		}
+		c, err := rl.r.ReadByte()
+		n := int(c)
+		switch {
+		case err != nil || n == 128: // EOD
+			rl.eod = true
+		case n < 128: // n+1 bytes to copy
+			b := make([]byte, n+1)
+			k, _ := io.ReadFull(rl.r, b)
+			rl.buf = b[:k]
+		default: // next byte 257-n times
+			b, err := rl.r.ReadByte()
+			if err != nil {
+				rl.eod = true
+				continue
+			}
+			rl.buf = bytes.Repeat([]byte{b}, 257-n)
+		}
+	}
+	n := copy(p, rl.buf)
+	rl.buf = rl.buf[n:]
+	return n, nil
+}
+
+// pngReader undoes the PNG predictors, row by row. Each row starts with
+// the predictor type.
+type pngReader struct {
+	r         io.Reader
+	bpp       int    // bytes per pixel, at least 1
+	row, prev []byte // current and previous row, with the type byte
+	buf       []byte // decoded data not yet returned
+	err       error
+}
+
+func newPNGReader(r io.Reader, colors, columns, bitspercomponent int) *pngReader {
+	n := (colors*bitspercomponent*columns + 7) / 8
+	return &pngReader{
+		r:    r,
+		bpp:  max(1, colors*bitspercomponent/8),
+		row:  make([]byte, n+1),
+		prev: make([]byte, n+1),
+	}
+}
+
+func (pr *pngReader) Read(p []byte) (int, error) {
+	for len(pr.buf) == 0 {
+		if pr.err != nil {
+			return 0, pr.err
+		}
+		pr.row, pr.prev = pr.prev, pr.row
+		n, err := io.ReadFull(pr.r, pr.row)
+		if err != nil {
+			// a short last row is decoded as far as it goes
+			pr.err = io.EOF
+			if n < 2 {
+				continue
+			}
+		}
+		cur, prev := pr.row[1:n], pr.prev[1:n]
+		switch pr.row[0] {
+		case 0: // none
+		case 1: // sub
+			for i := pr.bpp; i < len(cur); i++ {
+				cur[i] += cur[i-pr.bpp]
+			}
+		case 2: // up
+			for i := range cur {
+				cur[i] += prev[i]
+			}
+		case 3: // average
+			for i := range cur {
+				a := 0
+				if i >= pr.bpp {
+					a = int(cur[i-pr.bpp])
+				}
+				cur[i] += byte((a + int(prev[i])) / 2)
+			}
+		case 4: // paeth
+			for i := range cur {
+				var a, c byte
+				if i >= pr.bpp {
+					a, c = cur[i-pr.bpp], prev[i-pr.bpp]
+				}
+				cur[i] += paeth(a, prev[i], c)
+			}
+		default:
+			util.Log("Unsupported PNG predictor type", pr.row[0])
+			pr.err = ErrUnsupportedFilter
+			continue
+		}
+		pr.buf = cur
+	}
+	n := copy(p, pr.buf)
+	pr.buf = pr.buf[n:]
+	return n, nil
+}
+
+func paeth(a, b, c byte) byte {
+	abs := func(x int) int {
+		if x < 0 {
+			return -x
+		}
+		return x
+	}
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+// tiffReader undoes TIFF predictor 2: each sample is the difference to the
+// same component of the pixel to its left.
+type tiffReader struct {
+	r      io.Reader
+	colors int
+	bpc    int    // bits per component
+	n      int    // samples per row
+	row    []byte // current row
+	buf    []byte // decoded data not yet returned
+	err    error
+}
+
+func newTIFFReader(r io.Reader, colors, columns, bitspercomponent int) *tiffReader {
+	return &tiffReader{
+		r:      r,
+		colors: colors,
+		bpc:    bitspercomponent,
+		n:      colors * columns,
+		row:    make([]byte, (colors*bitspercomponent*columns+7)/8),
+	}
+}
+
+func (tr *tiffReader) Read(p []byte) (int, error) {
+	for len(tr.buf) == 0 {
+		if tr.err != nil {
+			return 0, tr.err
+		}
+		n, err := io.ReadFull(tr.r, tr.row)
+		if err != nil {
+			// a short last row is decoded as far as it goes
+			tr.err = io.EOF
+		}
+		row := tr.row[:n]
+		switch tr.bpc {
+		case 8:
+			for i := tr.colors; i < len(row); i++ {
+				row[i] += row[i-tr.colors]
+			}
+		case 16:
+			for i := 2 * tr.colors; i+1 < len(row); i += 2 {
+				k := i - 2*tr.colors
+				v := uint16(row[i])<<8 | uint16(row[i+1])
+				v += uint16(row[k])<<8 | uint16(row[k+1])
+				row[i], row[i+1] = byte(v>>8), byte(v)
+			}
+		default:
+			// samples of 1, 2 or 4 bits, high bits first
+			bpc := uint(tr.bpc)
+			mask := byte(1)<<bpc - 1
+			get := func(i int) byte {
+				p := uint(i) * bpc
+				return row[p>>3] >> (8 - bpc - p&7) & mask
+			}
+			for i := tr.colors; i < min(tr.n, len(row)*8/tr.bpc); i++ {
+				v := (get(i) + get(i-tr.colors)) & mask
+				p := uint(i) * bpc
+				shift := 8 - bpc - p&7
+				row[p>>3] = row[p>>3]&^(mask<<shift) | v<<shift
+			}
+		}
+		tr.buf = row
+	}
+	n := copy(p, tr.buf)
+	tr.buf = tr.buf[n:]
+	return n, nil
+}
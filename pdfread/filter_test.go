@@ -0,0 +1,121 @@
+package pdfread
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name  string
+		dic   string
+		data  []byte
+		want  []byte
+		codec string
+	}{
+		{"RunLength", "/Filter /RunLengthDecode", []byte{2, 'a', 'b', 'c', 254, 'x', 0, 'y', 128, 'z'}, []byte("abcxxxy"), ""},
+		{"RunLength no EOD", "/Filter /RunLengthDecode", []byte{255, '-', 1, 'o', 'k'}, []byte("--ok"), ""},
+		// H W3 B2 V0: 3 white, 2 black, 3 white pixels
+		{"CCITT", "/Filter /CCITTFaxDecode /DecodeParms << /K -1 /Columns 8 /Rows 1 >>", []byte{0x31, 0xc0}, []byte{0xe7}, ""},
+		{"CCITT BlackIs1", "/Filter /CCITTFaxDecode /DecodeParms << /K -1 /Columns 8 /Rows 1 /BlackIs1 true >>", []byte{0x31, 0xc0}, []byte{0x18}, ""},
+		{"DCT", "/Filter /DCTDecode", []byte{0xff, 0xd8, 0xff}, []byte{0xff, 0xd8, 0xff}, "/DCTDecode"},
+		{"JPX after hex", "/Filter [/ASCIIHexDecode /JPXDecode]", []byte("00 0C 6a>"), []byte{0, 0x0c, 0x6a}, "/JPXDecode"},
+		{"JBIG2", "/Filter /JBIG2Decode /DecodeParms << /JBIG2Globals 5 0 R >>", []byte{0x97, 'J', 'B'}, []byte{0x97, 'J', 'B'}, "/JBIG2Decode"},
+	}
+	for _, tt := range tests {
+		got, codec := decodeStream(Dictionary([]byte("<<"+tt.dic+">>")), tt.data)
+		if !bytes.Equal(got, tt.want) || codec != tt.codec {
+			t.Errorf("%s: got % x %q, want % x %q", tt.name, got, codec, tt.want, tt.codec)
+		}
+	}
+}
+
+func TestPredictors(t *testing.T) {
+	tests := []struct {
+		name string
+		parm string
+		data []byte
+		want []byte
+	}{
+		{"none", "/Predictor 1", []byte{1, 2, 3}, []byte{1, 2, 3}},
+		{"PNG", "/Predictor 12 /Columns 3", []byte{
+			0, 10, 20, 30, // none
+			1, 5, 5, 5, // sub
+			2, 1, 1, 1, // up
+			3, 4, 4, 4, // average
+			4, 1, 1, 1, // paeth
+		}, []byte{10, 20, 30, 5, 10, 15, 6, 11, 16, 7, 13, 18, 8, 14, 19}},
+		{"PNG 2 colors", "/Predictor 15 /Colors 2 /Columns 2", []byte{
+			1, 1, 2, 3, 4,
+			4, 1, 1, 1, 1,
+		}, []byte{1, 2, 4, 6, 2, 3, 5, 7}},
+		{"PNG 16 bits", "/Predictor 11 /Columns 2 /BitsPerComponent 16", []byte{
+			1, 1, 0xff, 0, 2,
+		}, []byte{1, 0xff, 1, 0x01}},
+		{"PNG short row", "/Predictor 10 /Columns 4", []byte{
+			0, 1, 2, 3, 4,
+			2, 1, 1,
+		}, []byte{1, 2, 3, 4, 2, 3}},
+		{"TIFF", "/Predictor 2 /Columns 4", []byte{1, 1, 1, 1, 5, 0xff, 1, 1}, []byte{1, 2, 3, 4, 5, 4, 5, 6}},
+		{"TIFF 3 colors", "/Predictor 2 /Colors 3 /Columns 2", []byte{10, 20, 30, 1, 2, 3}, []byte{10, 20, 30, 11, 22, 33}},
+		{"TIFF 16 bits", "/Predictor 2 /Columns 2 /BitsPerComponent 16", []byte{1, 0, 0, 0xff}, []byte{1, 0, 1, 0xff}},
+		{"TIFF 1 bit", "/Predictor 2 /Columns 8 /BitsPerComponent 1", []byte{0x80, 0x41}, []byte{0xff, 0x7e}},
+		{"TIFF 4 bits", "/Predictor 2 /Columns 3 /BitsPerComponent 4", []byte{0x21, 0xf0}, []byte{0x23, 0x20}},
+	}
+	for _, tt := range tests {
+		r, err := predictorReader(bytes.NewReader(tt.data), Dictionary([]byte("<<"+tt.parm+">>")))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: got % x, want % x", tt.name, got, tt.want)
+		}
+	}
+
+	for _, parm := range []string{
+		"/Predictor 12 /Colors 0",
+		"/Predictor 12 /Colors 33",
+		"/Predictor 2 /BitsPerComponent 3",
+		"/Predictor 12 /BitsPerComponent -8 /Columns 5",
+		"/Predictor 12 /BitsPerComponent 0",
+		"/Predictor 12 /Columns 9223372036854775807",
+		"/Predictor 12 /Colors 32 /BitsPerComponent 16 /Columns 16777216",
+		"/Predictor 7",
+	} {
+		if _, err := predictorReader(bytes.NewReader(nil), Dictionary([]byte("<<"+parm+">>"))); err != ErrUnsupportedFilter {
+			t.Errorf("%s: err = %v, want %v", parm, err, ErrUnsupportedFilter)
+		}
+	}
+}
+
+func TestDecodeParms(t *testing.T) {
+	tests := []struct {
+		dic  string
+		want []string // /Columns of each filter
+	}{
+		{"/Filter /FlateDecode /DecodeParms << /Columns 4 >>", []string{"4"}},
+		{"/Filter [/FlateDecode] /DecodeParms << /Columns 4 >>", []string{"4"}},
+		{"/Filter [/FlateDecode /LZWDecode] /DecodeParms [null << /Columns 4 >>]", []string{"", "4"}},
+		{"/Filter [/FlateDecode /LZWDecode] /DecodeParms [<< /Columns 3 >> << /Columns 4 >>]", []string{"3", "4"}},
+		{"/Filter [/ASCII85Decode /FlateDecode] /DecodeParms << /Columns 4 >>", []string{"", ""}},
+		{"/Filter [/FlateDecode /LZWDecode] /DecodeParms [<< /Columns 3 >>]", []string{"3", ""}},
+		{"/Filter [/FlateDecode] /DecodeParms [<< /Columns 3 >> << /Columns 4 >>]", []string{"3"}},
+	}
+	for _, tt := range tests {
+		dic := Dictionary([]byte("<<" + tt.dic + ">>"))
+		parms := decodeParms(dic, ForcedArray(dic["/Filter"]))
+		got := make([]string, len(parms))
+		for i, p := range parms {
+			got[i] = string(p["/Columns"])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: /Columns %q, want %q", tt.dic, got, tt.want)
+		}
+	}
+}
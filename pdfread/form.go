@@ -0,0 +1,325 @@
+package pdfread
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// field flags in Field.Flags (/Ff of the field).
+const (
+	FieldReadOnly = 1 << 0
+	FieldRequired = 1 << 1
+	FieldNoExport = 1 << 2
+
+	// text fields
+	FieldMultiline   = 1 << 12
+	FieldPassword    = 1 << 13
+	FieldFileSelect  = 1 << 20
+	FieldDoNotScroll = 1 << 23
+	FieldComb        = 1 << 24
+	FieldRichText    = 1 << 25
+
+	// buttons
+	FieldNoToggleToOff  = 1 << 14
+	FieldRadio          = 1 << 15
+	FieldPushbutton     = 1 << 16
+	FieldRadiosInUnison = 1 << 25
+
+	// choice fields
+	FieldCombo       = 1 << 17
+	FieldEdit        = 1 << 18
+	FieldSort        = 1 << 19
+	FieldMultiSelect = 1 << 21
+)
+
+// Form is the interactive form (/AcroForm) of the document.
+type Form struct {
+	NeedAppearances bool        // /NeedAppearances
+	Dict            DictionaryT // the /AcroForm dictionary
+	fields          []Field
+}
+
+// Field is a terminal field of a form, the one holding the value. The
+// inheritable entries (/FT, /Ff, /V, /DV, /Opt, /MaxLen) are taken from the
+// ancestors when the field has none.
+//
+// Values are strings: the text of text fields, the state name (without
+// '/') of buttons, mapped to the export value with /Opt, and the selected
+// options of choice fields. Signature fields have a Signature instead.
+type Field struct {
+	Name      string      // fully qualified name: the /T of the field and its ancestors, joined by dots
+	AltName   string      // /TU - name shown to the user
+	Type      string      // /FT: /Tx, /Btn, /Ch or /Sig
+	Flags     int         // /Ff - see the Field* flags
+	Value     string      // /V, the first selected option for choice fields
+	Values    []string    // /V of choice fields, all selected options
+	Default   string      // /DV, as Value
+	Checked   bool        // check boxes and radio buttons: Value is an on-state
+	Options   []Option    // /Opt of choice fields, export values of buttons
+	MaxLen    int         // /MaxLen of text fields, 0 if none
+	Signature *Signature  // signature fields: /V, nil if not signed
+	Widgets   []Widget    // the widget annotations of the field
+	Ref       []byte      // reference of the field, nil if it's a direct object
+	Dict      DictionaryT // the field dictionary with the inherited entries
+}
+
+// Option is an entry of /Opt.
+type Option struct {
+	Export  string // value of the option in Field.Value
+	Display string // text shown to the user, the export value if there is none
+}
+
+// Widget is a widget annotation of a field, the field itself for merged
+// field and widget dictionaries.
+type Widget struct {
+	Page    int       // index of the page, -1 if it's not found
+	Rect    Rectangle // /Rect
+	OnState string    // buttons: the value when on, see pd.onState(), mapped as Field.Value
+	Ref     []byte    // reference of the widget, nil if it's a direct object
+}
+
+// Signature is the signature dictionary of a signed signature field.
+type Signature struct {
+	Name        string      // /Name - the signer
+	Reason      string      // /Reason
+	Location    string      // /Location
+	ContactInfo string      // /ContactInfo
+	Date        time.Time   // /M, zero if missing
+	Filter      string      // /Filter - the signature handler
+	SubFilter   string      // /SubFilter - the signature encoding
+	Dict        DictionaryT // the signature dictionary
+}
+
+// pd.Form() returns the interactive form of the document, nil if there
+// is none.
+func (pd *PdfReaderT) Form() *Form {
+	d := pd.Dic(pd.Dic(pd.Trailer["/Root"])["/AcroForm"])
+	if d == nil {
+		return nil
+	}
+	f := &Form{NeedAppearances: string(pd.Obj(d["/NeedAppearances"])) == "true", Dict: d}
+
+	// pages of the widgets from /Annots, /P is optional
+	pages := make(map[string]int)
+	for i, pg := range pd.Pages() {
+		for _, a := range pd.Arr(pd.Dic(pg)["/Annots"]) {
+			if r := normRef(a); r != nil {
+				pages[string(r)] = i
+			}
+		}
+	}
+
+	done := make(map[int]bool)
+	for _, r := range pd.Arr(d["/Fields"]) {
+		f.fields = pd.formFields(r, done, pages, 0, f.fields)
+	}
+	return f
+}
+
+// f.Fields() returns the terminal fields of the form, in the order of the
+// field tree.
+func (f *Form) Fields() []Field {
+	return f.fields
+}
+
+// f.Field() returns the field with a fully qualified name, nil if there is
+// none.
+func (f *Form) Field(name string) *Field {
+	for i := range f.fields {
+		if f.fields[i].Name == name {
+			return &f.fields[i]
+		}
+	}
+	return nil
+}
+
+// pd.formFields() appends the terminal fields at and below a node of the
+// field tree. Kids without /T and /Kids are widgets of the node. done holds
+// the nodes seen, against loops.
+func (pd *PdfReaderT) formFields(ref []byte, done map[int]bool, pages map[string]int, depth int, fields []Field) []Field {
+	if n, _ := refNums(ref); n >= 0 {
+		if done[n] {
+			return fields
+		}
+		done[n] = true
+	}
+	d := pd.Dic(ref)
+	if d == nil || depth > MAX_TREE_DEPTH {
+		return fields
+	}
+
+	var kids, widgets [][]byte
+	for _, k := range pd.Arr(d["/Kids"]) {
+		kd := pd.Dic(k)
+		if kd == nil {
+			continue
+		}
+		_, named := kd["/T"]
+		_, parent := kd["/Kids"]
+		if named || parent {
+			kids = append(kids, k)
+		} else {
+			widgets = append(widgets, k)
+		}
+	}
+	if len(kids) == 0 && len(widgets) == 0 {
+		widgets = [][]byte{ref}
+	}
+
+	if len(widgets) > 0 {
+		fields = append(fields, pd.field(ref, d, widgets, pages))
+	}
+	for _, k := range kids {
+		fields = pd.formFields(k, done, pages, depth+1, fields)
+	}
+	return fields
+}
+
+// pd.field() reads a terminal field with its widgets.
+func (pd *PdfReaderT) field(ref []byte, d DictionaryT, widgets [][]byte, pages map[string]int) Field {
+	f := Field{
+		Name:    pd.fieldName(d),
+		AltName: pd.Text(d["/TU"]),
+		Ref:     normRef(ref),
+		Dict:    make(DictionaryT, len(d)+6),
+	}
+	for k, v := range d {
+		f.Dict[k] = v
+	}
+	for _, k := range []string{"/FT", "/Ff", "/V", "/DV", "/Opt", "/MaxLen"} {
+		if v := pd.Attribute(k, ref); len(v) > 0 {
+			f.Dict[k] = v
+		}
+	}
+	f.Type = string(pd.Obj(f.Dict["/FT"]))
+	f.Flags = pd.Num(f.Dict["/Ff"])
+	if f.Type == "/Tx" {
+		f.MaxLen = pd.Num(f.Dict["/MaxLen"])
+	}
+
+	for _, o := range pd.Arr(f.Dict["/Opt"]) {
+		var opt Option
+		if pair := pd.Arr(o); len(pair) == 2 {
+			opt = Option{pd.Text(pair[0]), pd.Text(pair[1])}
+		} else {
+			opt.Export = pd.Text(o)
+			opt.Display = opt.Export
+		}
+		f.Options = append(f.Options, opt)
+	}
+
+	for _, w := range widgets {
+		wd := pd.Dic(w)
+		wi := Widget{Page: -1, Ref: normRef(w)}
+		wi.Rect, _ = pd.rectangle(wd["/Rect"])
+		if i, ok := pages[string(wi.Ref)]; ok {
+			wi.Page = i
+		} else if p, ok := wd["/P"]; ok {
+			wi.Page = pd.pageIndex(p)
+		}
+		if f.Type == "/Btn" {
+			wi.OnState = f.buttonState([]byte(pd.onState(wd)))
+		}
+		f.Widgets = append(f.Widgets, wi)
+	}
+
+	switch f.Type {
+	case "/Sig":
+		if s := pd.Dic(f.Dict["/V"]); s != nil {
+			f.Signature = &Signature{
+				Name:        pd.Text(s["/Name"]),
+				Reason:      pd.Text(s["/Reason"]),
+				Location:    pd.Text(s["/Location"]),
+				ContactInfo: pd.Text(s["/ContactInfo"]),
+				Filter:      string(pd.Obj(s["/Filter"])),
+				SubFilter:   string(pd.Obj(s["/SubFilter"])),
+				Dict:        s,
+			}
+			f.Signature.Date, _ = ParseDate(pd.Text(s["/M"]))
+		}
+	case "/Ch":
+		f.Values = pd.fieldValues(f.Dict["/V"])
+		if len(f.Values) > 0 {
+			f.Value = f.Values[0]
+		}
+		if dv := pd.fieldValues(f.Dict["/DV"]); len(dv) > 0 {
+			f.Default = dv[0]
+		}
+	case "/Btn":
+		f.Value = f.buttonState(pd.Obj(f.Dict["/V"]))
+		f.Default = f.buttonState(pd.Obj(f.Dict["/DV"]))
+		f.Checked = f.Flags&FieldPushbutton == 0 && f.Value != "" && f.Value != "Off"
+	default:
+		f.Value = pd.fieldText(f.Dict["/V"])
+		f.Default = pd.fieldText(f.Dict["/DV"])
+	}
+	return f
+}
+
+// pd.onState() returns the name of the on appearance state of a button
+// widget: of the states other than /Off in /AP /N and /AP /D, the current
+// one (/AS) if it's there, else the first in byte order. It's "" if there
+// are none.
+func (pd *PdfReaderT) onState(wd DictionaryT) string {
+	var states []string
+	ap := pd.Dic(wd["/AP"])
+	for _, k := range []string{"/N", "/D"} {
+		for state := range pd.Dic(ap[k]) {
+			if state != "/Off" {
+				states = append(states, state)
+			}
+		}
+	}
+	if len(states) == 0 {
+		return ""
+	}
+	as := string(pd.Obj(wd["/AS"]))
+	for _, s := range states {
+		if s == as {
+			return as
+		}
+	}
+	sort.Strings(states)
+	return states[0]
+}
+
+// f.buttonState() returns the state name of a button value, mapped to the
+// export value if the states are indices into /Opt.
+func (f *Field) buttonState(v []byte) string {
+	if len(v) == 0 || v[0] != '/' {
+		return ""
+	}
+	s := string(NormalizeName(v)[1:])
+	if i, err := strconv.Atoi(s); err == nil && i >= 0 && i < len(f.Options) {
+		return f.Options[i].Export
+	}
+	return s
+}
+
+// pd.fieldText() returns the value of a text field: a text string or a
+// stream.
+func (pd *PdfReaderT) fieldText(reference []byte) string {
+	if _, ok := pd.Value(reference).(Stream); ok {
+		_, data := pd.DecodedStream(reference)
+		return DecodeText(data)
+	}
+	return pd.Text(reference)
+}
+
+// pd.fieldValues() returns the selected options of a choice field: a text
+// string or an array of them.
+func (pd *PdfReaderT) fieldValues(reference []byte) []string {
+	v := pd.Obj(reference)
+	if len(v) == 0 {
+		return nil
+	}
+	if v[0] != '[' {
+		return []string{pd.Text(v)}
+	}
+	var r []string
+	for _, s := range Array(v) {
+		r = append(r, pd.Text(s))
+	}
+	return r
+}
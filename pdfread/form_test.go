@@ -0,0 +1,102 @@
+package pdfread
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestForm reads the fields of form.pdf: text fields with inherited
+// entries, a check box, radio buttons with /Opt, a list, a signature and
+// field trees with a loop.
+func TestForm(t *testing.T) {
+	pd, err := Open("testdata/form.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	form := pd.Form()
+	if form == nil || !form.NeedAppearances {
+		t.Fatalf("Form() = %+v", form)
+	}
+	names := []string{"a.b", "a.c", "agree", "color", "list", "sig", "loop.x.y", "grp.r"}
+	fields := form.Fields()
+	if len(fields) != len(names) {
+		t.Fatalf("%d fields", len(fields))
+	}
+	for i, f := range fields {
+		if f.Name != names[i] {
+			t.Errorf("field %d: %q, want %q", i, f.Name, names[i])
+		}
+	}
+
+	tests := []struct {
+		name, typ, value, def string
+		flags, maxLen         int
+		checked               bool
+		pages                 []int
+	}{
+		{"a.b", "/Tx", "hello (world)", "x", FieldRequired, 20, false, []int{0}},
+		{"a.c", "/Tx", "line1\nl2\n", "", FieldReadOnly | FieldMultiline, 0, false, []int{0, 1}},
+		{"agree", "/Btn", "Yes", "", 0, 0, true, []int{0}},
+		{"color", "/Btn", "green", "", FieldRadio | FieldNoToggleToOff, 0, true, []int{0, 1}},
+		{"list", "/Ch", "b", "", FieldMultiSelect, 0, false, []int{1}},
+		{"sig", "/Sig", "", "", 0, 0, false, []int{-1}},
+		{"loop.x.y", "/Tx", "inh", "", 0, 0, false, []int{-1}},
+		{"grp.r", "/Btn", "no", "", FieldRadio | FieldNoToggleToOff, 0, true, []int{-1, -1, -1, -1}},
+	}
+	for _, tt := range tests {
+		f := form.Field(tt.name)
+		if f == nil {
+			t.Errorf("no field %s", tt.name)
+			continue
+		}
+		if f.Type != tt.typ || f.Value != tt.value || f.Default != tt.def || f.Flags != tt.flags || f.MaxLen != tt.maxLen || f.Checked != tt.checked {
+			t.Errorf("%s: %s %q %q %#x %d %v", tt.name, f.Type, f.Value, f.Default, f.Flags, f.MaxLen, f.Checked)
+		}
+		var pages []int
+		for _, w := range f.Widgets {
+			pages = append(pages, w.Page)
+		}
+		if !reflect.DeepEqual(pages, tt.pages) {
+			t.Errorf("%s: widgets on pages %v, want %v", tt.name, pages, tt.pages)
+		}
+	}
+
+	if f := form.Field("a.b"); f.AltName != "The B field" || string(f.Ref) != "11 0 R" || string(f.Widgets[0].Ref) != "11 0 R" ||
+		f.Widgets[0].Rect != (Rectangle{10, 10, 100, 30}) {
+		t.Errorf("a.b: %+v", f)
+	}
+	if f := form.Field("color"); f.Widgets[0].OnState != "red" || f.Widgets[1].OnState != "green" ||
+		!reflect.DeepEqual(f.Options, []Option{{"red", "red"}, {"green", "green"}}) {
+		t.Errorf("color: %+v", f)
+	}
+	var states []string
+	for _, w := range form.Field("grp.r").Widgets {
+		states = append(states, w.OnState)
+	}
+	if want := []string{"yes", "no", "On", "zz"}; !reflect.DeepEqual(states, want) {
+		t.Errorf("grp.r: on-states %q, want %q", states, want)
+	}
+	if f := form.Field("list"); !reflect.DeepEqual(f.Values, []string{"b", "c"}) ||
+		!reflect.DeepEqual(f.Options, []Option{{"a", "Alpha"}, {"b", "Beta"}, {"c", "c"}}) {
+		t.Errorf("list: %+v", f)
+	}
+	s := form.Field("sig").Signature
+	if s == nil || s.Name != "J. Doe" || s.Reason != "I agree" || s.Filter != "/Adobe.PPKLite" ||
+		s.SubFilter != "/adbe.pkcs7.detached" || !s.Date.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("signature %+v", s)
+	}
+	if f := form.Field("a"); f != nil {
+		t.Errorf("Field(a) = %+v", f)
+	}
+
+	pd, err = Open("testdata/plain.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := pd.Form(); f != nil {
+		t.Errorf("plain.pdf: Form() = %+v", f)
+	}
+	pd.Close()
+}
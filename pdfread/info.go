@@ -0,0 +1,358 @@
+package pdfread
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/raff/pdfreader/ps"
+)
+
+// Info is the document information dictionary (/Info in the trailer).
+type Info struct {
+	Title        string
+	Author       string
+	Subject      string
+	Keywords     string
+	Creator      string            // application that created the original document
+	Producer     string            // application that converted it to PDF
+	CreationDate time.Time         // zero if missing or invalid
+	ModDate      time.Time         // zero if missing or invalid
+	Custom       map[string]string // other entries, by name without '/'
+}
+
+// XMP holds the properties of an XMP metadata packet, for the Dublin Core
+// (dc:), XMP basic (xmp:), Adobe PDF (pdf:) and PDF/A identification
+// (pdfaid:) schemas.
+type XMP struct {
+	Title        string   // dc:title, the x-default language first
+	Creator      []string // dc:creator - the authors
+	Description  string   // dc:description
+	Subject      []string // dc:subject - keywords
+	Rights       string   // dc:rights
+	Format       string   // dc:format
+	CreatorTool  string   // xmp:CreatorTool
+	CreateDate   time.Time
+	ModifyDate   time.Time
+	MetadataDate time.Time
+	Producer     string // pdf:Producer
+	Keywords     string // pdf:Keywords
+	PDFVersion   string // pdf:PDFVersion
+	Part         int    // pdfaid:part, 0 if not PDF/A
+	Conformance  string // pdfaid:conformance
+	// all simple properties by namespace URI and name, as in
+	// "http://purl.org/dc/elements/1.1/title"
+	Properties map[string][]string
+	Raw        []byte // the packet
+}
+
+// XMP namespaces
+const (
+	_NS_RDF    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	_NS_DC     = "http://purl.org/dc/elements/1.1/"
+	_NS_XMP    = "http://ns.adobe.com/xap/1.0/"
+	_NS_PDF    = "http://ns.adobe.com/pdf/1.3/"
+	_NS_PDFAID = "http://www.aiim.org/pdfa/ns/id/"
+	_NS_XML    = "http://www.w3.org/XML/1998/namespace"
+)
+
+// PDFDocEncoding where it differs from Latin-1, 0 for undefined codes
+var pdfDocEncoding = map[byte]rune{
+	0x18: '˘', 0x19: 'ˇ', 0x1a: 'ˆ', 0x1b: '˙',
+	0x1c: '˝', 0x1d: '˛', 0x1e: '˚', 0x1f: '˜',
+	0x7f: 0,
+	0x80: '•', 0x81: '†', 0x82: '‡', 0x83: '…',
+	0x84: '—', 0x85: '–', 0x86: 'ƒ', 0x87: '⁄',
+	0x88: '‹', 0x89: '›', 0x8a: '−', 0x8b: '‰',
+	0x8c: '„', 0x8d: '“', 0x8e: '”', 0x8f: '‘',
+	0x90: '’', 0x91: '‚', 0x92: '™', 0x93: 'ﬁ',
+	0x94: 'ﬂ', 0x95: 'Ł', 0x96: 'Œ', 0x97: 'Š',
+	0x98: 'Ÿ', 0x99: 'Ž', 0x9a: 'ı', 0x9b: 'ł',
+	0x9c: 'œ', 0x9d: 'š', 0x9e: 'ž', 0x9f: 0,
+	0xa0: '€', 0xad: 0,
+}
+
+// DecodeText() converts a text string (the bytes from ps.String()) to
+// UTF-8. Text strings are UTF-16BE with a byte order mark, UTF-8 with a
+// byte order mark (PDF 2.0) or PDFDocEncoding.
+func DecodeText(b []byte) string {
+	switch {
+	case len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff:
+		u := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(u))
+	case len(b) >= 3 && b[0] == 0xef && b[1] == 0xbb && b[2] == 0xbf:
+		return string(b[3:])
+	}
+	r := make([]rune, 0, len(b))
+	for _, c := range b {
+		if u, ok := pdfDocEncoding[c]; !ok {
+			r = append(r, rune(c))
+		} else if u != 0 {
+			r = append(r, u)
+		} else {
+			r = append(r, utf8.RuneError)
+		}
+	}
+	return string(r)
+}
+
+// ParseDate() parses a PDF date: D:YYYYMMDDHHmmSSOHH'mm', where all fields
+// after the year are optional. The time zone is UTC if it's missing.
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	f := []int{0, 1, 1, 0, 0, 0} // year, month, day, hour, minute, second
+	w := []int{4, 2, 2, 2, 2, 2}
+	i := 0
+	for k := range f {
+		if i+w[k] > len(s) || !isDigits(s[i:i+w[k]]) {
+			if k == 0 {
+				return time.Time{}, false
+			}
+			break
+		}
+		f[k], _ = strconv.Atoi(s[i : i+w[k]])
+		i += w[k]
+	}
+
+	loc := time.UTC
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		z := strings.Replace(s[i+1:], "'", "", -1)
+		h, m := 0, 0
+		if len(z) >= 2 && isDigits(z[:2]) {
+			h, _ = strconv.Atoi(z[:2])
+		}
+		if len(z) >= 4 && isDigits(z[2:4]) {
+			m, _ = strconv.Atoi(z[2:4])
+		}
+		offs := h*3600 + m*60
+		if s[i] == '-' {
+			offs = -offs
+		}
+		loc = time.FixedZone("", offs)
+	}
+	if f[1] < 1 || f[1] > 12 || f[2] < 1 || f[2] > 31 || f[3] > 23 || f[4] > 59 || f[5] > 60 {
+		return time.Time{}, false
+	}
+	return time.Date(f[0], time.Month(f[1]), f[2], f[3], f[4], f[5], 0, loc), true
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// pd.Text() returns a text string object as UTF-8, "" if it's not a string.
+func (pd *PdfReaderT) Text(reference []byte) string {
+	s := pd.Obj(reference)
+	if len(s) == 0 || (s[0] != '(' && s[0] != '<') || bytes.HasPrefix(s, []byte("<<")) {
+		return ""
+	}
+	return DecodeText(ps.String(s))
+}
+
+// pd.Info() returns the document information dictionary, nil if there is
+// none.
+func (pd *PdfReaderT) Info() *Info {
+	d := pd.Dic(pd.Trailer["/Info"])
+	if d == nil {
+		return nil
+	}
+	info := &Info{Custom: make(map[string]string)}
+	for k, v := range d {
+		switch k {
+		case "/Title":
+			info.Title = pd.Text(v)
+		case "/Author":
+			info.Author = pd.Text(v)
+		case "/Subject":
+			info.Subject = pd.Text(v)
+		case "/Keywords":
+			info.Keywords = pd.Text(v)
+		case "/Creator":
+			info.Creator = pd.Text(v)
+		case "/Producer":
+			info.Producer = pd.Text(v)
+		case "/CreationDate":
+			info.CreationDate, _ = ParseDate(pd.Text(v))
+		case "/ModDate":
+			info.ModDate, _ = ParseDate(pd.Text(v))
+		default:
+			name := string(NormalizeName([]byte(k))[1:])
+			if t := pd.Text(v); t != "" {
+				info.Custom[name] = t
+			} else if v := pd.Obj(v); len(v) > 0 && v[0] == '/' {
+				// names like /Trapped
+				info.Custom[name] = string(NormalizeName(v)[1:])
+			}
+		}
+	}
+	return info
+}
+
+// xmlNodeT is an element of an XML document.
+type xmlNodeT struct {
+	name     xml.Name
+	attr     []xml.Attr
+	text     string
+	children []*xmlNodeT
+}
+
+func parseXML(data []byte) (*xmlNodeT, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	root := &xmlNodeT{}
+	stack := []*xmlNodeT{root}
+	for {
+		t, err := d.Token()
+		if err != nil {
+			if len(stack) == 1 && err == io.EOF {
+				return root, nil
+			}
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := t.(type) {
+		case xml.StartElement:
+			n := &xmlNodeT{name: t.Name, attr: t.Attr}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			top.text += string(t)
+		}
+	}
+}
+
+func (n *xmlNodeT) is(ns, local string) bool {
+	return n.name.Space == ns && n.name.Local == local
+}
+
+func (n *xmlNodeT) attribute(ns, local string) (string, bool) {
+	for _, a := range n.attr {
+		if a.Name.Space == ns && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// n.values() returns the values of an RDF property: its text, its resource
+// or the items of an rdf:Alt, rdf:Seq or rdf:Bag. The x-default item of an
+// rdf:Alt is first. Structured values are skipped.
+func (n *xmlNodeT) values() []string {
+	if r, ok := n.attribute(_NS_RDF, "resource"); ok {
+		return []string{r}
+	}
+	if len(n.children) == 0 {
+		return []string{strings.TrimSpace(n.text)}
+	}
+	var r []string
+	for _, c := range n.children {
+		if !c.is(_NS_RDF, "Alt") && !c.is(_NS_RDF, "Seq") && !c.is(_NS_RDF, "Bag") {
+			continue
+		}
+		for _, li := range c.children {
+			if !li.is(_NS_RDF, "li") || len(li.children) > 0 {
+				continue
+			}
+			v := strings.TrimSpace(li.text)
+			if lang, _ := li.attribute(_NS_XML, "lang"); lang == "x-default" {
+				r = append([]string{v}, r...)
+			} else {
+				r = append(r, v)
+			}
+		}
+	}
+	return r
+}
+
+// properties() collects the properties of all rdf:Description elements.
+func (n *xmlNodeT) properties(p map[string][]string) {
+	if !n.is(_NS_RDF, "Description") {
+		for _, c := range n.children {
+			c.properties(p)
+		}
+		return
+	}
+	for _, a := range n.attr {
+		switch a.Name.Space {
+		case _NS_RDF, _NS_XML, "xmlns", "":
+		default:
+			p[a.Name.Space+a.Name.Local] = append(p[a.Name.Space+a.Name.Local], a.Value)
+		}
+	}
+	for _, c := range n.children {
+		if v := c.values(); len(v) > 0 {
+			p[c.name.Space+c.name.Local] = append(p[c.name.Space+c.name.Local], v...)
+		}
+	}
+}
+
+// parseXMPDate() parses the ISO 8601 dates of XMP, from just the year up
+// to fractions of seconds.
+func parseXMPDate(s string) time.Time {
+	for _, f := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(f, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// pd.XMP() returns the XMP metadata of the document (/Metadata of the
+// catalog), nil if there is none. An error is returned for bad XML.
+func (pd *PdfReaderT) XMP() (*XMP, error) {
+	m, ok := pd.Dic(pd.Trailer["/Root"])["/Metadata"]
+	if !ok {
+		return nil, nil
+	}
+	_, data := pd.DecodedStream(m)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	x := &XMP{Properties: make(map[string][]string), Raw: data}
+	root.properties(x.Properties)
+	first := func(ns, name string) string {
+		if v := x.Properties[ns+name]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	x.Title = first(_NS_DC, "title")
+	x.Creator = x.Properties[_NS_DC+"creator"]
+	x.Description = first(_NS_DC, "description")
+	x.Subject = x.Properties[_NS_DC+"subject"]
+	x.Rights = first(_NS_DC, "rights")
+	x.Format = first(_NS_DC, "format")
+	x.CreatorTool = first(_NS_XMP, "CreatorTool")
+	x.CreateDate = parseXMPDate(first(_NS_XMP, "CreateDate"))
+	x.ModifyDate = parseXMPDate(first(_NS_XMP, "ModifyDate"))
+	x.MetadataDate = parseXMPDate(first(_NS_XMP, "MetadataDate"))
+	x.Producer = first(_NS_PDF, "Producer")
+	x.Keywords = first(_NS_PDF, "Keywords")
+	x.PDFVersion = first(_NS_PDF, "PDFVersion")
+	x.Part, _ = strconv.Atoi(first(_NS_PDFAID, "part"))
+	x.Conformance = first(_NS_PDFAID, "conformance")
+	return x, nil
+}
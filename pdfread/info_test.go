@@ -0,0 +1,111 @@
+package pdfread
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		b    string
+		want string
+	}{
+		{"plain", "plain"},
+		{"\xfe\xff\x00T\x00\xef\x26\x03", "Tï☃"},
+		{"\xfe\xff\xd8\x3d\xde\x00", "😀"},
+		{"\xef\xbb\xbfcaf\xc3\xa9", "café"},
+		{"\x95ukasz \x80 \x84 caf\xe9", "Łukasz • — café"},
+		{"a\x7fb\x9f\xad", "a�b��"},
+		{"\x18\xa0", "˘€"},
+	}
+	for _, tt := range tests {
+		if got := DecodeText([]byte(tt.b)); got != tt.want {
+			t.Errorf("DecodeText(%q) = %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Time
+		ok   bool
+	}{
+		{"D:20200102030405+05'30'", time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 5*3600+30*60)), true},
+		{"D:20200102030405-08'00", time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", -8*3600)), true},
+		{"D:20200102030405Z", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"D:2019", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"199912", time.Date(1999, 12, 1, 0, 0, 0, 0, time.UTC), true},
+		{"D:20201301", time.Time{}, false},
+		{"D:", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDate(tt.s)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, %v; want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestInfo reads the /Info dictionary and the XMP packet of info.pdf.
+func TestInfo(t *testing.T) {
+	pd, err := Open("testdata/info.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+
+	info := pd.Info()
+	if info == nil {
+		t.Fatal("no /Info")
+	}
+	want := &Info{
+		Title:        "Tïtle ☃",
+		Author:       "Łukasz • — café",
+		Subject:      "indirect subject",
+		CreationDate: time.Date(2020, 1, 2, 3, 4, 5, 0, time.FixedZone("", 5*3600+30*60)),
+		ModDate:      time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		Custom:       map[string]string{"Trapped": "False", "Custom1": "hello"},
+	}
+	if info.Title != want.Title || info.Author != want.Author || info.Subject != want.Subject ||
+		!info.CreationDate.Equal(want.CreationDate) || !info.ModDate.Equal(want.ModDate) ||
+		!reflect.DeepEqual(info.Custom, want.Custom) {
+		t.Errorf("Info() = %+v, want %+v", info, want)
+	}
+
+	x, err := pd.XMP()
+	if err != nil || x == nil {
+		t.Fatalf("XMP() = %v, %v", x, err)
+	}
+	if x.Title != "The Title" || !reflect.DeepEqual(x.Creator, []string{"Ann", "Bob & Co"}) ||
+		!reflect.DeepEqual(x.Subject, []string{"alpha", "beta"}) || x.Format != "application/pdf" {
+		t.Errorf("dc: %q %q %q %q", x.Title, x.Creator, x.Subject, x.Format)
+	}
+	if x.Producer != "XProd 1.0" || x.Keywords != "alpha, beta" || x.CreatorTool != "Writer" {
+		t.Errorf("pdf: and xmp: %q %q %q", x.Producer, x.Keywords, x.CreatorTool)
+	}
+	if !x.CreateDate.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("", 2*3600))) ||
+		!x.ModifyDate.Equal(time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)) || !x.MetadataDate.IsZero() {
+		t.Errorf("dates %v %v %v", x.CreateDate, x.ModifyDate, x.MetadataDate)
+	}
+	if x.Part != 2 || x.Conformance != "B" {
+		t.Errorf("PDF/A-%d%s", x.Part, x.Conformance)
+	}
+	if v := x.Properties[_NS_DC+"title"]; len(v) != 2 || v[1] != "Der Titel" {
+		t.Errorf("dc:title %q", v)
+	}
+
+	pd, err = Open("testdata/plain.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, err := pd.XMP(); x != nil || err != nil {
+		t.Errorf("plain.pdf: XMP() = %v, %v", x, err)
+	}
+	if info := pd.Info(); info == nil || info.Title != "Hello (World)" || info.Author != "Me" {
+		t.Errorf("plain.pdf: Info() = %+v", info)
+	}
+	pd.Close()
+}
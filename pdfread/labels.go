@@ -0,0 +1,133 @@
+package pdfread
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pd.PageLabels() returns the labels of the pages as shown by viewers,
+// from the /PageLabels number tree of the catalog. Pages without a label
+// range get their page number, starting at 1.
+func (pd *PdfReaderT) PageLabels() []string {
+	return append([]string(nil), pd.pageLabels()...)
+}
+
+// pd.pageLabels() returns the cached labels for pd.PageLabels(), read
+// once.
+func (pd *PdfReaderT) pageLabels() []string {
+	pd.mu.Lock()
+	cached := pd.labels
+	pd.mu.Unlock()
+	if cached != nil {
+		return cached
+	}
+
+	n := len(pd.Pages())
+	labels := make([]string, n)
+	for i := range labels {
+		labels[i] = strconv.Itoa(i + 1)
+	}
+
+	type rangeT struct {
+		start int
+		dic   DictionaryT
+	}
+	var ranges []rangeT
+	tree := pd.NumberTree(pd.Dic(pd.Trailer["/Root"])["/PageLabels"])
+	tree.Each(func(k int, v []byte) bool {
+		if k >= 0 && k < n {
+			ranges = append(ranges, rangeT{k, pd.Dic(v)})
+		}
+		return true
+	})
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	for i, r := range ranges {
+		end := n
+		if i+1 < len(ranges) {
+			end = ranges[i+1].start
+		}
+		prefix := pd.Text(r.dic["/P"])
+		style := string(pd.Obj(r.dic["/S"]))
+		first := 1
+		if st, ok := r.dic["/St"]; ok {
+			first = pd.Num(st)
+		}
+		for p := r.start; p < end; p++ {
+			labels[p] = prefix + labelNumber(style, first+p-r.start)
+		}
+	}
+
+	pd.mu.Lock()
+	pd.labels = labels
+	pd.mu.Unlock()
+	return labels
+}
+
+// pd.PageLabel() returns the label of page i (counting from 0, as in
+// pd.Pages()), "" if there is no such page.
+func (pd *PdfReaderT) PageLabel(i int) string {
+	labels := pd.pageLabels()
+	if i < 0 || i >= len(labels) {
+		return ""
+	}
+	return labels[i]
+}
+
+// pd.PageByLabel() returns the index of the first page with the label,
+// -1 if there is none.
+func (pd *PdfReaderT) PageByLabel(label string) int {
+	for i, l := range pd.pageLabels() {
+		if l == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// labelNumber() formats the numeric part of a page label: /D decimal,
+// /R and /r roman, /A and /a letters (A to Z, then AA to ZZ, ...), nothing
+// without a style. Numbers out of range for roman numerals and letters
+// are decimal.
+func labelNumber(style string, n int) string {
+	switch style {
+	case "/D":
+		return strconv.Itoa(n)
+	case "/R", "/r", "/A", "/a":
+		if n < 1 || n > MAX_LABEL_NUMBER {
+			return strconv.Itoa(n)
+		}
+	default:
+		return ""
+	}
+
+	var s string
+	if style == "/R" || style == "/r" {
+		s = roman(n)
+	} else {
+		s = strings.Repeat(string(rune('a'+(n-1)%26)), (n-1)/26+1)
+	}
+	if style == "/R" || style == "/A" {
+		s = strings.ToUpper(s)
+	}
+	return s
+}
+
+// roman() returns n as a lowercase roman numeral.
+func roman(n int) string {
+	var b strings.Builder
+	for _, d := range []struct {
+		v int
+		s string
+	}{
+		{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"},
+		{100, "c"}, {90, "xc"}, {50, "l"}, {40, "xl"},
+		{10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+	} {
+		for ; n >= d.v; n -= d.v {
+			b.WriteString(d.s)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package pdfread
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabelNumber(t *testing.T) {
+	tests := []struct {
+		style string
+		n     int
+		want  string
+	}{
+		{"/D", 7, "7"},
+		{"/r", 14, "xiv"},
+		{"/R", 1994, "MCMXCIV"},
+		{"/R", MAX_LABEL_NUMBER + 1, "10000"},
+		{"/r", 0, "0"},
+		{"/a", 26, "z"},
+		{"/A", 27, "AA"},
+		{"/a", 53, "aaa"},
+		{"", 5, ""},
+		{"/X", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := labelNumber(tt.style, tt.n); got != tt.want {
+			t.Errorf("labelNumber(%s, %d) = %q, want %q", tt.style, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestPageLabels reads the labels of the 12 pages of labels.pdf, from a
+// number tree with two leaves and a range past the last page.
+func TestPageLabels(t *testing.T) {
+	pd, err := Open("testdata/labels.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	want := []string{"i", "ii", "iii", "1", "2", "A-1", "A-2", "A-3", "Cover", "Z", "AA", "ÜMMMCMXCIX"}
+	labels := pd.PageLabels()
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("PageLabels() = %q, want %q", labels, want)
+	}
+	labels[0] = "changed"
+	if l := pd.PageLabel(0); l != "i" {
+		t.Errorf("PageLabels() returned its cached slice: PageLabel(0) = %q", l)
+	}
+	if l := pd.PageLabel(12); l != "" {
+		t.Errorf("PageLabel(12) = %q", l)
+	}
+	for _, tt := range []struct {
+		label string
+		page  int
+	}{{"A-2", 6}, {"1", 3}, {"Cover", 8}, {"12", -1}, {"", -1}} {
+		if p := pd.PageByLabel(tt.label); p != tt.page {
+			t.Errorf("PageByLabel(%q) = %d, want %d", tt.label, p, tt.page)
+		}
+	}
+
+	pd, err = Open("testdata/plain.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := pd.PageLabels(); !reflect.DeepEqual(l, []string{"1"}) {
+		t.Errorf("plain.pdf: PageLabels() = %q", l)
+	}
+	pd.Close()
+}
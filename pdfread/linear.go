@@ -0,0 +1,339 @@
+package pdfread
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/raff/pdfreader/ps"
+	"github.com/raff/pdfreader/util"
+)
+
+// Linearization holds the linearization parameters and hint tables of a
+// linearized ("fast web view") file.
+type Linearization struct {
+	Object         int          // object number of the linearization dictionary
+	Length         int64        // /L - length of the file
+	Hint           [2]int64     // /H - position and length of the primary hint stream
+	FirstPage      int          // /O - object number of the first page
+	EndOfFirstPage int64        // /E - end of the first page section
+	Pages          int          // /N - number of pages
+	MainXref       int64        // /T - position of the main xref
+	FirstPageNum   int          // /P - index of the first page, usually 0
+	Problems       []string     // parameters not matching the file, empty if valid
+	PageHints      []PageHint   // page offset hint table
+	SharedHints    []SharedHint // shared object hint table
+}
+
+// PageHint is the entry of a page in the page offset hint table. Positions
+// are file offsets.
+type PageHint struct {
+	Objects       int   // number of objects of the page
+	Offset        int64 // position of the first object of the page
+	Length        int64 // length of the page's objects
+	Shared        []int // indexes of the shared object groups used
+	ContentOffset int64 // offset of the content stream from Offset
+	ContentLength int64 // length of the content stream
+}
+
+// SharedHint is the entry of a shared object group in the shared object
+// hint table. Object and Offset are -1 for the groups of the first page
+// section.
+type SharedHint struct {
+	Object    int    // number of the first object of the group
+	Offset    int64  // position of the group
+	Length    int64  // length of the group
+	Objects   int    // number of objects in the group
+	Signature []byte // MD5 signature of the group, nil if none
+}
+
+// pd.Linearization() returns the linearization parameters of the file, nil
+// if it's not linearized. The parameters are checked against the file, see
+// Problems. An error is returned for broken hint tables.
+func (pd *PdfReaderT) Linearization() (*Linearization, error) {
+	// the linearization dictionary is the first object in the file
+	f := pd.at(0)
+	m := tuple(f, 3)
+	if string(m[2]) != "obj" {
+		return nil, nil
+	}
+	t, p := ps.Token(f)
+	dic := Dictionary(t)
+	if _, ok := dic["/Linearized"]; !ok || p > 1024 {
+		return nil, nil
+	}
+
+	l := &Linearization{
+		Object:         num(m[0]),
+		Length:         int64(num(dic["/L"])),
+		FirstPage:      num(dic["/O"]),
+		EndOfFirstPage: int64(num(dic["/E"])),
+		Pages:          num(dic["/N"]),
+		MainXref:       int64(num(dic["/T"])),
+		FirstPageNum:   num(dic["/P"]),
+	}
+	if h := Array(dic["/H"]); len(h) >= 2 {
+		l.Hint = [2]int64{int64(num(h[0])), int64(num(h[1]))}
+	}
+	pd.checkLinearization(l)
+
+	if err := pd.readHints(l); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// pd.checkLinearization() compares the linearization parameters with the
+// file.
+func (pd *PdfReaderT) checkLinearization(l *Linearization) {
+	problem := func(f string, args ...interface{}) {
+		l.Problems = append(l.Problems, fmt.Sprintf(f, args...))
+	}
+
+	if l.Length != pd.Size {
+		problem("/L %d, file length %d (updated after linearization?)", l.Length, pd.Size)
+	}
+	if l.EndOfFirstPage <= 0 || l.EndOfFirstPage > pd.Size {
+		problem("/E %d outside of the file", l.EndOfFirstPage)
+	} else if p, ok := pd.Xref[l.FirstPage]; ok && int64(p) >= l.EndOfFirstPage {
+		problem("first page at %d, after /E %d", p, l.EndOfFirstPage)
+	}
+
+	pages := pd.Pages()
+	if l.Pages != len(pages) {
+		problem("/N %d, %d pages", l.Pages, len(pages))
+	}
+	if l.FirstPageNum < 0 || l.FirstPageNum >= len(pages) {
+		problem("/P %d out of range", l.FirstPageNum)
+	} else if o, _ := refNums(pages[l.FirstPageNum]); o != l.FirstPage {
+		problem("/O %d, first page is object %d", l.FirstPage, o)
+	}
+
+	if m := tuple(pd.at(int(l.Hint[0])), 3); l.Hint[1] <= 0 || l.Hint[0]+l.Hint[1] > pd.Size || string(m[2]) != "obj" {
+		problem("/H [%d %d] is no hint stream", l.Hint[0], l.Hint[1])
+	}
+
+	// the main xref is the oldest section
+	secs, err := xrefChain(pd.at(0), pd.Startxref)
+	if err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = pd.File
+		}
+		problem("bad xref: %v", err)
+		return
+	}
+	main := secs[len(secs)-1]
+	if main.stream {
+		if l.MainXref != int64(main.pos) {
+			problem("/T %d, main xref stream at %d", l.MainXref, main.pos)
+		}
+	} else {
+		// /T is the white-space before the first entry of the table
+		f := pd.at(main.pos)
+		tuple(f, 3)
+		if q, _ := f.Seek(0, 1); l.MainXref < q || l.MainXref > q+2 {
+			problem("/T %d, main xref entries at %d", l.MainXref, q)
+		}
+	}
+}
+
+// bitReaderT reads the bit fields of the hint tables.
+type bitReaderT struct {
+	b   []byte
+	pos int // position in bits
+}
+
+func (br *bitReaderT) read(n int) int64 {
+	r := int64(0)
+	for ; n > 0; n-- {
+		if br.pos>>3 >= len(br.b) {
+			br.pos++
+			continue
+		}
+		r = r<<1 | int64(br.b[br.pos>>3]>>(7-uint(br.pos&7))&1)
+		br.pos++
+	}
+	return r
+}
+
+// align() skips to the next byte boundary.
+func (br *bitReaderT) align() {
+	br.pos = (br.pos + 7) &^ 7
+}
+
+// left() returns the number of bits not read yet.
+func (br *bitReaderT) left() int {
+	return max(0, len(br.b)*8-br.pos)
+}
+
+func (br *bitReaderT) eof() bool {
+	return br.pos > len(br.b)*8
+}
+
+// pd.readHints() reads the page offset and shared object hint tables of the
+// primary hint stream.
+func (pd *PdfReaderT) readHints(l *Linearization) error {
+	f := pd.at(int(l.Hint[0]))
+	m := tuple(f, 3)
+	if string(m[2]) != "obj" {
+		return parseError(pd.File, l.Hint[0], ErrBadHints)
+	}
+	dic, data := pd.DecodedStream([]byte(fmt.Sprintf("%d %d R", num(m[0]), num(m[1]))))
+	s := num(dic["/S"])
+	if dic == nil || s <= 0 || s > len(data) {
+		return parseError(pd.File, l.Hint[0], ErrBadHints)
+	}
+
+	// hint table positions are counted without the primary hint stream
+	pos := func(p int64) int64 {
+		if p >= l.Hint[0] {
+			p += l.Hint[1]
+		}
+		return p
+	}
+
+	// page offset hint table
+	br := &bitReaderT{b: data[:s]}
+	minObjs := br.read(32)
+	first := br.read(32)
+	objBits := int(br.read(16))
+	minLen := br.read(32)
+	lenBits := int(br.read(16))
+	minCOffs := br.read(32)
+	cOffsBits := int(br.read(16))
+	minCLen := br.read(32)
+	cLenBits := int(br.read(16))
+	nSharedBits := int(br.read(16))
+	sharedBits := int(br.read(16))
+	numerBits := int(br.read(16))
+	br.read(16) // denominator
+
+	for _, b := range []int{objBits, lenBits, cOffsBits, cLenBits, nSharedBits, sharedBits, numerBits} {
+		if b > 32 {
+			return parseError(pd.File, l.Hint[0], ErrBadHints)
+		}
+	}
+	n := l.Pages
+	if n <= 0 || n > len(data)*8 {
+		return parseError(pd.File, l.Hint[0], ErrBadHints)
+	}
+	ph := make([]PageHint, n)
+	for i := range ph {
+		ph[i].Objects = int(minObjs + br.read(objBits))
+	}
+	br.align()
+	for i := range ph {
+		ph[i].Length = minLen + br.read(lenBits)
+		ph[i].Offset = pos(first)
+		first += ph[i].Length
+	}
+	br.align()
+	shared := 0
+	for i := range ph {
+		// the identifiers of the groups follow, sharedBits each
+		k := int(br.read(nSharedBits))
+		if shared += k; shared > br.left()/max(sharedBits, 1) {
+			util.Log("page offset hint table too short for", shared, "shared groups")
+			return parseError(pd.File, l.Hint[0], ErrBadHints)
+		}
+		ph[i].Shared = make([]int, k)
+	}
+	br.align()
+	for i := range ph {
+		for k := range ph[i].Shared {
+			ph[i].Shared[k] = int(br.read(sharedBits))
+		}
+	}
+	br.align()
+	for i := range ph {
+		for range ph[i].Shared {
+			br.read(numerBits) // position in the page, for incremental display
+		}
+	}
+	br.align()
+	for i := range ph {
+		ph[i].ContentOffset = minCOffs + br.read(cOffsBits)
+	}
+	br.align()
+	for i := range ph {
+		ph[i].ContentLength = minCLen + br.read(cLenBits)
+	}
+	if br.eof() {
+		util.Log("page offset hint table too short")
+		return parseError(pd.File, l.Hint[0], ErrBadHints)
+	}
+	l.PageHints = ph
+
+	// shared object hint table
+	br = &bitReaderT{b: data[s:]}
+	firstObj := int(br.read(32))
+	firstOffs := br.read(32)
+	nFirst := int(br.read(32))
+	nGroups := int(br.read(32))
+	grpBits := int(br.read(16))
+	minGrpLen := br.read(32)
+	grpLenBits := int(br.read(16))
+
+	// each group has at least a bit, its signature flag
+	if nGroups < 0 || nGroups > br.left() || nFirst > nGroups || grpBits > 32 || grpLenBits > 32 {
+		return parseError(pd.File, l.Hint[0], ErrBadHints)
+	}
+	sh := make([]SharedHint, nGroups)
+	for i := range sh {
+		sh[i].Length = minGrpLen + br.read(grpLenBits)
+	}
+	br.align()
+	signed := make([]bool, nGroups)
+	for i := range sh {
+		signed[i] = br.read(1) == 1
+	}
+	br.align()
+	for i := range sh {
+		if signed[i] {
+			sh[i].Signature = make([]byte, 16)
+			for k := range sh[i].Signature {
+				sh[i].Signature[k] = byte(br.read(8))
+			}
+		}
+	}
+	br.align()
+	for i := range sh {
+		sh[i].Objects = int(br.read(grpBits)) + 1
+	}
+	if br.eof() {
+		util.Log("shared object hint table too short")
+		return parseError(pd.File, l.Hint[0], ErrBadHints)
+	}
+	for i := range sh {
+		if i < nFirst {
+			sh[i].Object, sh[i].Offset = -1, -1
+			continue
+		}
+		sh[i].Object, sh[i].Offset = firstObj, pos(firstOffs)
+		firstObj += sh[i].Objects
+		firstOffs += sh[i].Length
+	}
+	l.SharedHints = sh
+	return nil
+}
+
+// l.PageRanges() returns the byte ranges of the file needed to display page
+// i: the objects of the page and the shared objects it uses. For the first
+// page this is the start of the file up to /E.
+func (l *Linearization) PageRanges(i int) [][2]int64 {
+	if i == l.FirstPageNum {
+		return [][2]int64{{0, l.EndOfFirstPage}}
+	}
+	if i < 0 || i >= len(l.PageHints) {
+		return nil
+	}
+	p := l.PageHints[i]
+	r := [][2]int64{{p.Offset, p.Offset + p.Length}}
+	for _, s := range p.Shared {
+		if s >= 0 && s < len(l.SharedHints) && l.SharedHints[s].Offset >= 0 {
+			g := l.SharedHints[s]
+			r = append(r, [2]int64{g.Offset, g.Offset + g.Length})
+		}
+	}
+	return r
+}
@@ -0,0 +1,110 @@
+package pdfread
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestLinearization reads lin.pdf, a linearized file with 3 pages sharing
+// the form XObject 6, and copies of it with wrong parameters.
+func TestLinearization(t *testing.T) {
+	lin := readFile(t, "lin.pdf")
+	pd, err := OpenBytes(lin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := pd.Linearization()
+	if err != nil || l == nil {
+		t.Fatalf("Linearization() = %v, %v", l, err)
+	}
+	if len(l.Problems) != 0 {
+		t.Errorf("problems: %q", l.Problems)
+	}
+	if l.Object != 10 || l.FirstPage != 12 || l.Pages != 3 || l.Length != int64(len(lin)) {
+		t.Errorf("parameters %+v", l)
+	}
+	if len(l.PageHints) != 3 || len(l.SharedHints) != 2 {
+		t.Fatalf("%d page hints, %d shared hints", len(l.PageHints), len(l.SharedHints))
+	}
+	for i, p := range l.PageHints[1:] {
+		o, _ := refNums(pd.Pages()[i+1])
+		if p.Offset != int64(pd.Xref[o]) || len(p.Shared) != 1 || p.Shared[0] != 1 {
+			t.Errorf("page %d: %+v, page object at %d", i+1, p, pd.Xref[o])
+		}
+	}
+	if g := l.SharedHints[1]; g.Object != 6 || g.Offset != int64(pd.Xref[6]) || g.Objects != 1 {
+		t.Errorf("shared group 1: %+v", g)
+	}
+	if r := l.PageRanges(0); len(r) != 1 || r[0] != [2]int64{0, l.EndOfFirstPage} {
+		t.Errorf("PageRanges(0) = %v", r)
+	}
+	if r := l.PageRanges(2); len(r) != 2 || r[1][0] != int64(pd.Xref[6]) {
+		t.Errorf("PageRanges(2) = %v", r)
+	}
+	if r := l.PageRanges(3); r != nil {
+		t.Errorf("PageRanges(3) = %v", r)
+	}
+	pd.Close()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		problem string
+	}{
+		{"/N", bytes.Replace(lin, []byte("/N 3"), []byte("/N 4"), 1), "/N 4, 3 pages"},
+		{"update", readFile(t, "linupd.pdf"), "/L 1776"},
+		{"xref", bytes.Replace(lin, []byte("startxref\n131"), []byte("startxref\n151"), 1), "bad xref: lin: "},
+	}
+	for _, tt := range tests {
+		pd, err := NewReaderOptions(bytes.NewReader(tt.data), int64(len(tt.data)), "lin", &Options{Repair: true})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		l, _ := pd.Linearization()
+		if l == nil || !strings.HasPrefix(strings.Join(l.Problems, "\n"), tt.problem) {
+			t.Errorf("%s: problems %q, want %q", tt.name, l.Problems, tt.problem)
+		}
+		pd.Close()
+	}
+
+	pd, err = Open("testdata/plain.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, err := pd.Linearization(); l != nil || err != nil {
+		t.Errorf("plain.pdf: %v, %v", l, err)
+	}
+	pd.Close()
+}
+
+// TestBadHints changes the field sizes and counts of the hint tables of
+// lin.pdf, so they claim more entries than the stream holds.
+func TestBadHints(t *testing.T) {
+	lin := readFile(t, "lin.pdf")
+	h := bytes.Index(lin, []byte("/S 0000000057"))
+	h += bytes.Index(lin[h:], []byte("stream\n")) + 7
+	tests := []struct {
+		name string
+		at   int // in the hint stream
+		b    []byte
+	}{
+		{"shared object count bits", 28, []byte{0, 32}},     // of the page offset table
+		{"shared group count", 57 + 12, []byte{0xff, 0xff}}, // 0x00ff_ff02 groups
+		{"object bits", 8, []byte{0, 33}},
+	}
+	for _, tt := range tests {
+		data := append([]byte(nil), lin...)
+		copy(data[h+tt.at:], tt.b)
+		pd, err := OpenBytes(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if _, err := pd.Linearization(); !errors.Is(err, ErrBadHints) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, ErrBadHints)
+		}
+		pd.Close()
+	}
+}
@@ -0,0 +1,358 @@
+package pdfread
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/raff/pdfreader/ps"
+	"github.com/raff/pdfreader/util"
+)
+
+// Object is a typed PDF object: Null, Bool, Integer, Real, Name, String,
+// ArrayT, Dict, Ref or Stream.
+//
+// The array type is ArrayT, not Array: Array() is the function splitting
+// the bytes of an array, and it keeps its name since callers use it, like
+// DictionaryT and Dictionary().
+type Object interface {
+	pdfObject()
+}
+
+type (
+	Null    struct{}
+	Bool    bool
+	Integer int64
+	Real    float64
+	Name    string   // with leading '/' and #xx escapes resolved
+	String  []byte   // decoded contents of a literal or hex string
+	ArrayT  []Object // typed array, see Object for the name
+	Dict    map[Name]Object
+)
+
+// Ref is a reference to an indirect object. Refs from pd.Value() know
+// their reader, so the accessors of Dict and ArrayT resolve them.
+type Ref struct {
+	Num, Gen int
+	pd       *PdfReaderT
+}
+
+// Stream is a stream object: its dictionary and the reference to read
+// the data with.
+type Stream struct {
+	Dict Dict
+	Ref  Ref
+}
+
+func (Null) pdfObject()    {}
+func (Bool) pdfObject()    {}
+func (Integer) pdfObject() {}
+func (Real) pdfObject()    {}
+func (Name) pdfObject()    {}
+func (String) pdfObject()  {}
+func (ArrayT) pdfObject()  {}
+func (Dict) pdfObject()    {}
+func (Ref) pdfObject()     {}
+func (Stream) pdfObject()  {}
+
+// NormalizeName() returns a name token with #xx escapes resolved.
+func NormalizeName(s []byte) Name {
+	if bytes.IndexByte(s, '#') < 0 {
+		return Name(s)
+	}
+	return Name(util.Unescape(s))
+}
+
+// Parse() converts PDF data (as returned by pd.Obj() or found in
+// DictionaryT) into a typed object. References in the result can't be
+// resolved, use pd.Value() for that.
+func Parse(s []byte) Object {
+	return parse(nil, s)
+}
+
+func parse(pd *PdfReaderT, s []byte) Object {
+	if len(s) == 0 {
+		return Null{}
+	}
+	switch s[0] {
+	case '/':
+		return NormalizeName(s)
+	case '(':
+		return String(ps.String(s))
+	case '<':
+		if len(s) > 1 && s[1] == '<' {
+			d := Dictionary(s)
+			r := make(Dict, len(d))
+			for k, v := range d {
+				r[NormalizeName([]byte(k))] = parse(pd, v)
+			}
+			return r
+		}
+		return String(ps.String(s))
+	case '[':
+		a := Array(s)
+		r := make(ArrayT, len(a))
+		for i, v := range a {
+			r[i] = parse(pd, v)
+		}
+		return r
+	}
+	switch string(s) {
+	case "true":
+		return Bool(true)
+	case "false":
+		return Bool(false)
+	case "null":
+		return Null{}
+	}
+	if s[len(s)-1] == 'R' {
+		if o, g := refNums(s); o >= 0 {
+			return Ref{Num: o, Gen: g, pd: pd}
+		}
+	}
+	if i, err := strconv.ParseInt(string(s), 10, 64); err == nil {
+		return Integer(i)
+	}
+	if f, err := strconv.ParseFloat(string(s), 64); err == nil {
+		return Real(f)
+	}
+	util.Log("unexpected token", string(s))
+	return Null{}
+}
+
+// pd.Value() resolves a reference (or takes direct PDF data) and returns
+// the typed object. Stream objects are returned as Stream.
+func (pd *PdfReaderT) Value(reference []byte) Object {
+	n, s := pd.Resolve(reference)
+	o := parse(pd, s)
+	if d, ok := o.(Dict); ok && n >= 0 {
+		if r, ok := parse(pd, reference).(Ref); ok && pd.isStream(n) {
+			return Stream{Dict: d, Ref: r}
+		}
+	}
+	return o
+}
+
+// pd.isStream() checks for the "stream" keyword at a position.
+func (pd *PdfReaderT) isStream(n int) bool {
+	t, _ := ps.Token(pd.at(n))
+	return string(t) == "stream"
+}
+
+// pd.Dict() queries typed dictionary data from a reference.
+func (pd *PdfReaderT) Dict(reference []byte) Dict {
+	switch o := pd.Value(reference).(type) {
+	case Dict:
+		return o
+	case Stream:
+		return o.Dict
+	}
+	return nil
+}
+
+// pd.TrailerDict() returns the trailer dictionary as typed object.
+func (pd *PdfReaderT) TrailerDict() Dict {
+	r := make(Dict, len(pd.Trailer))
+	for k, v := range pd.Trailer {
+		r[NormalizeName([]byte(k))] = parse(pd, v)
+	}
+	return r
+}
+
+func (Null) String() string { return "null" }
+
+func (s String) String() string { return string(s) }
+
+// Bytes() returns the reference as PDF data ("N G R") for the byte APIs.
+func (r Ref) Bytes() []byte {
+	return []byte(r.String())
+}
+
+func (r Ref) String() string {
+	return fmt.Sprintf("%d %d R", r.Num, r.Gen)
+}
+
+// Resolve() returns the referenced object, Null if it can't be resolved.
+func (r Ref) Resolve() Object {
+	if r.pd == nil {
+		return Null{}
+	}
+	return r.pd.Value(r.Bytes())
+}
+
+// Resolve() resolves o if it's a reference.
+func Resolve(o Object) Object {
+	if r, ok := o.(Ref); ok {
+		return r.Resolve()
+	}
+	if o == nil {
+		return Null{}
+	}
+	return o
+}
+
+func toInt(o Object) (int, bool) {
+	switch v := Resolve(o).(type) {
+	case Integer:
+		return int(v), true
+	case Real:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func toFloat(o Object) (float64, bool) {
+	switch v := Resolve(o).(type) {
+	case Integer:
+		return float64(v), true
+	case Real:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toDict(o Object) Dict {
+	switch v := Resolve(o).(type) {
+	case Dict:
+		return v
+	case Stream:
+		return v.Dict
+	}
+	return nil
+}
+
+// d.Get() returns the resolved value of a key, Null if missing.
+func (d Dict) Get(key Name) Object {
+	return Resolve(d[key])
+}
+
+// d.Has() checks if a key is present.
+func (d Dict) Has(key Name) bool {
+	_, ok := d[key]
+	return ok
+}
+
+// d.Int() returns a number as int, 0 if it's not a number.
+func (d Dict) Int(key Name) int {
+	i, _ := toInt(d[key])
+	return i
+}
+
+// d.IntDefault() returns a number as int, def if it's not a number.
+func (d Dict) IntDefault(key Name, def int) int {
+	if i, ok := toInt(d[key]); ok {
+		return i
+	}
+	return def
+}
+
+// d.Float() returns a number as float64, 0 if it's not a number.
+func (d Dict) Float(key Name) float64 {
+	f, _ := toFloat(d[key])
+	return f
+}
+
+// d.Name() returns a name, "" if it's not a name.
+func (d Dict) Name(key Name) Name {
+	n, _ := d.Get(key).(Name)
+	return n
+}
+
+// d.Str() returns a string, nil if it's not a string.
+func (d Dict) Str(key Name) String {
+	s, _ := d.Get(key).(String)
+	return s
+}
+
+// d.Bool() returns a boolean, false if it's not a boolean.
+func (d Dict) Bool(key Name) bool {
+	b, _ := d.Get(key).(Bool)
+	return bool(b)
+}
+
+// d.Array() returns an array, nil if it's not an array.
+func (d Dict) Array(key Name) ArrayT {
+	a, _ := d.Get(key).(ArrayT)
+	return a
+}
+
+// d.Dict() returns a dictionary (or the dictionary of a stream), nil if
+// it's neither.
+func (d Dict) Dict(key Name) Dict {
+	return toDict(d[key])
+}
+
+// d.Stream() returns a stream, false if it's not a stream.
+func (d Dict) Stream(key Name) (Stream, bool) {
+	s, ok := d.Get(key).(Stream)
+	return s, ok
+}
+
+// d.Ref() returns the unresolved reference of a key, false if the value
+// is direct.
+func (d Dict) Ref(key Name) (Ref, bool) {
+	r, ok := d[key].(Ref)
+	return r, ok
+}
+
+// a.Get() returns the resolved element i, Null if out of range.
+func (a ArrayT) Get(i int) Object {
+	if i < 0 || i >= len(a) {
+		return Null{}
+	}
+	return Resolve(a[i])
+}
+
+// a.Int() returns element i as int, 0 if it's not a number.
+func (a ArrayT) Int(i int) int {
+	n, _ := toInt(a.Get(i))
+	return n
+}
+
+// a.Float() returns element i as float64, 0 if it's not a number.
+func (a ArrayT) Float(i int) float64 {
+	f, _ := toFloat(a.Get(i))
+	return f
+}
+
+// a.Name() returns element i as name, "" if it's not a name.
+func (a ArrayT) Name(i int) Name {
+	n, _ := a.Get(i).(Name)
+	return n
+}
+
+// a.Dict() returns element i as dictionary, nil if it's not one.
+func (a ArrayT) Dict(i int) Dict {
+	return toDict(a.Get(i))
+}
+
+// a.Floats() returns the numbers of an array, nil if there are others.
+func (a ArrayT) Floats() []float64 {
+	r := make([]float64, len(a))
+	for i := range a {
+		f, ok := toFloat(a[i])
+		if !ok {
+			return nil
+		}
+		r[i] = f
+	}
+	return r
+}
+
+// s.Raw() returns the (decrypted) stream data without applying filters.
+func (s Stream) Raw() []byte {
+	if s.Ref.pd == nil {
+		return nil
+	}
+	_, data := s.Ref.pd.Stream(s.Ref.Bytes())
+	return data
+}
+
+// s.Decoded() returns the decoded stream data.
+func (s Stream) Decoded() []byte {
+	if s.Ref.pd == nil {
+		return nil
+	}
+	_, data := s.Ref.pd.DecodedStream(s.Ref.Bytes())
+	return data
+}
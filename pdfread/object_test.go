@@ -0,0 +1,100 @@
+package pdfread
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Object
+	}{
+		{"", Null{}},
+		{"null", Null{}},
+		{"true", Bool(true)},
+		{"false", Bool(false)},
+		{"-12", Integer(-12)},
+		{"+3", Integer(3)},
+		{"3.5", Real(3.5)},
+		{"-.25", Real(-0.25)},
+		{"/Name", Name("/Name")},
+		{"/A#20B", Name("/A B")},
+		{"(a\\(b\\)\\n)", String("a(b)\n")},
+		{"<48 65 6c6c6f>", String("Hello")},
+		{"<4>", String("@")},
+		{"12 0 R", Ref{Num: 12}},
+		{"[1 2.5 /N (s) [true] 3 1 R]", ArrayT{Integer(1), Real(2.5), Name("/N"), String("s"), ArrayT{Bool(true)}, Ref{Num: 3, Gen: 1}}},
+		{"<< /Type /Page /A#42 [0 0 612 792] /D << /X null >> >>", Dict{
+			"/Type": Name("/Page"),
+			"/AB":   ArrayT{Integer(0), Integer(0), Integer(612), Integer(792)},
+			"/D":    Dict{"/X": Null{}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := Parse([]byte(tt.s)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	pd, err := Open("testdata/plain.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+
+	trailer := pd.TrailerDict()
+	root, ok := trailer.Ref("/Root")
+	if !ok || root.String() != "1 0 R" {
+		t.Errorf("/Root = %v", trailer["/Root"])
+	}
+	if trailer.Dict("/Root").Name("/Type") != "/Catalog" {
+		t.Errorf("/Root isn't the catalog: %v", trailer.Dict("/Root"))
+	}
+	if _, ok := trailer.Ref("/Size"); ok || trailer.Int("/Size") != 6 {
+		t.Errorf("/Size = %v", trailer["/Size"])
+	}
+	if n := trailer.IntDefault("/Missing", -1); n != -1 {
+		t.Errorf("IntDefault() = %d", n)
+	}
+
+	info := trailer.Dict("/Info")
+	if s := info.Str("/Title"); s.String() != "Hello (World)" {
+		t.Errorf("/Title = %q", s)
+	}
+	if s := info.Str("/Author"); s.String() != "Me" {
+		t.Errorf("/Author = %q", s)
+	}
+	if info.Has("/Subject") || info.Get("/Subject") != (Null{}) {
+		t.Errorf("/Subject = %v", info.Get("/Subject"))
+	}
+
+	kids := pd.Dict([]byte("2 0 R")).Array("/Kids")
+	page := kids.Dict(0)
+	if got := page.Array("/MediaBox").Floats(); !reflect.DeepEqual(got, []float64{0, 0, 612, 792}) {
+		t.Errorf("/MediaBox = %v", got)
+	}
+	if kids.Get(1) != (Null{}) || kids.Int(5) != 0 {
+		t.Errorf("out of range elements: %v", kids)
+	}
+
+	s, ok := page.Stream("/Contents")
+	if !ok || s.Ref.String() != "4 0 R" {
+		t.Fatalf("/Contents = %#v", page.Get("/Contents"))
+	}
+	if s.Dict.Name("/Filter") != "/FlateDecode" {
+		t.Errorf("stream dictionary %v", s.Dict)
+	}
+	if d := s.Decoded(); !bytes.Contains(d, []byte("(Hello) Tj")) {
+		t.Errorf("decoded contents %q", d)
+	}
+	if r := s.Raw(); len(r) != s.Dict.Int("/Length") {
+		t.Errorf("raw contents of %d bytes, /Length %d", len(r), s.Dict.Int("/Length"))
+	}
+	if _, ok := pd.Value([]byte("3 0 R")).(Dict); !ok {
+		t.Errorf("3 0 R = %#v", pd.Value([]byte("3 0 R")))
+	}
+}
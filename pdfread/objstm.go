@@ -0,0 +1,96 @@
+package pdfread
+
+import (
+	"strconv"
+
+	"github.com/raff/pdfreader/fancy"
+	"github.com/raff/pdfreader/util"
+)
+
+// objStmT is a decoded object stream (/Type /ObjStm).
+type objStmT struct {
+	num   int      // object number of the stream
+	data  []byte   // decoded data
+	first int      // /First - offset of the first object
+	index [][]byte // pairs of object number and offset
+}
+
+// pd.objStm() returns the decoded object stream n. The most recently used
+// streams are kept, so reading objects in order decodes each stream once.
+func (pd *PdfReaderT) objStm(n int) *objStmT {
+	key := strconv.Itoa(n)
+	if s, ok := pd.objstms.get(key); ok {
+		return s.(*objStmT)
+	}
+	// object streams can't be compressed, this also avoids endless loops
+	if _, ok := pd.Xref[n]; !ok {
+		util.Log("object stream", n, "not found")
+		return nil
+	}
+	// checked before decoding, pd.streamObj() relies on it
+	if string(pd.Dic(pd.ref(n))["/Type"]) != "/ObjStm" {
+		util.Log("not an object stream", n)
+		return nil
+	}
+	dic, data := pd.DecodedStream(pd.ref(n))
+	util.Log("Object-Stream", n)
+	// each entry takes at least 4 bytes: "o p "
+	count := min(max(0, num(dic["/N"])), (len(data)+1)/4)
+	s := &objStmT{
+		num:   n,
+		data:  data,
+		first: num(dic["/First"]),
+		index: tuple(fancy.SliceReader(data), count*2),
+	}
+	pd.objstms.put(key, s)
+	return s
+}
+
+// pd.streamObj() resolves an entry of a stream dictionary, like /Length
+// or /Filter. Object streams take them from uncompressed objects only
+// (null otherwise): a compressed one could be in the object stream itself.
+func (pd *PdfReaderT) streamObj(dic DictionaryT, v []byte) []byte {
+	if string(dic["/Type"]) != "/ObjStm" {
+		return pd.Obj(v)
+	}
+	done := make(map[int]bool)
+	for {
+		o, g := refNums(v)
+		if o < 0 {
+			return v
+		}
+		if _, ok := pd.Xref[o]; !ok || done[o] {
+			util.Log("object stream entry not in the xref", string(v))
+			return _Bytes
+		}
+		done[o] = true
+		_, v = pd.object(o, g)
+	}
+}
+
+// pd.compressed() extracts object o from its object stream, nil if the
+// object is not compressed or not found.
+func (pd *PdfReaderT) compressed(o int) []byte {
+	loc, ok := pd.Compressed[o]
+	if !ok {
+		return nil
+	}
+	s := pd.objStm(loc[0])
+	if s == nil {
+		return nil
+	}
+	i := 2 * loc[1]
+	if i+1 >= len(s.index) || num(s.index[i]) != o {
+		// wrong index, look for the object number
+		for i = 0; i+1 < len(s.index) && num(s.index[i]) != o; i += 2 {
+		}
+		if i+1 >= len(s.index) {
+			util.Log("object", o, "not in object stream", s.num)
+			return nil
+		}
+	}
+	rdr := fancy.SliceReader(s.data)
+	rdr.Seek(int64(s.first+num(s.index[i+1])), 0)
+	r, _ := refToken(rdr)
+	return r
+}
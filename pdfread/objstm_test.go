@@ -0,0 +1,38 @@
+package pdfread
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjStm(t *testing.T) {
+	tests := []struct {
+		file    string
+		warning string // expected warning, "" for none
+	}{
+		{"objstm.pdf", ""},                     // /Length in an uncompressed object
+		{"objstm_n.pdf", ""},                   // /N 10^18
+		{"objstm_loop.pdf", "missing /Length"}, // /Length in the object stream
+		{"hybrid.pdf", ""},
+	}
+	for _, tt := range tests {
+		pd, err := Open("testdata/" + tt.file)
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if typ := string(pd.Dic([]byte("2 0 R"))["/Type"]); typ != "/Pages" {
+			t.Errorf("%s: object 2 has /Type %q", tt.file, typ)
+		}
+		if tt.file != "hybrid.pdf" {
+			if v := string(pd.Obj([]byte("4 0 R"))); v != "12345" {
+				t.Errorf("%s: object 4 = %q", tt.file, v)
+			}
+		}
+		w := strings.Join(pd.Warnings(), "\n")
+		if tt.warning == "" && w != "" || !strings.Contains(w, tt.warning) {
+			t.Errorf("%s: warnings %q, want %q", tt.file, w, tt.warning)
+		}
+		pd.Close()
+	}
+}
@@ -0,0 +1,227 @@
+package pdfread
+
+import (
+	"bytes"
+	"math"
+
+	"github.com/raff/pdfreader/ps"
+)
+
+// Outline is an item of the document outline (bookmarks).
+type Outline struct {
+	Title    string
+	Page     int          // index of the target page, -1 if none
+	Ref      []byte       // reference of the target page, nil if none
+	Dest     *Destination // /Dest, or the target of a /GoTo action
+	Action   *Action      // /A, nil if none
+	Open     bool         // the children are shown (/Count > 0)
+	Color    []float64    // /C - RGB, nil for the default (black)
+	Italic   bool         // /F bit 1
+	Bold     bool         // /F bit 2
+	Children []Outline
+}
+
+// Destination is a view of a page. Params are the numbers after the view
+// type, NaN for null (unchanged).
+type Destination struct {
+	Page   int       // index of the page, -1 if unknown; page number for remote files
+	Ref    []byte    // reference of the page, nil for remote files
+	View   string    // /XYZ, /Fit, /FitH, /FitV, /FitR, /FitB, /FitBH or /FitBV
+	Params []float64 // /XYZ: left top zoom, /FitH: top, /FitR: left bottom right top, ...
+	Name   string    // name of a named destination, "" for an explicit one
+}
+
+// Action is an action dictionary, as in /A of outlines and links.
+type Action struct {
+	Type string       // /S: /GoTo, /GoToR, /Launch, /URI, /Named, ...
+	Dest *Destination // target of /GoTo, /GoToR and /GoToE
+	URI  string       // target of /URI
+	File string       // file of /GoToR, /GoToE and /Launch
+	Name string       // /N of /Named: /NextPage, /PrevPage, /FirstPage, /LastPage
+	Dict DictionaryT  // the action dictionary
+}
+
+// pd.pageIndex() returns the page index of a page reference, -1 if it's
+// not a page. The index of the pages by object number is built once.
+func (pd *PdfReaderT) pageIndex(ref []byte) int {
+	o, _ := refNums(ref)
+	if o < 0 {
+		return -1
+	}
+	pd.mu.Lock()
+	index := pd.pageNums
+	pd.mu.Unlock()
+	if index == nil {
+		pages := pd.Pages()
+		index = make(map[int]int, len(pages))
+		for i := len(pages) - 1; i >= 0; i-- {
+			if p, _ := refNums(pages[i]); p >= 0 {
+				index[p] = i
+			}
+		}
+		pd.mu.Lock()
+		pd.pageNums = index
+		pd.mu.Unlock()
+	}
+	if i, ok := index[o]; ok {
+		return i
+	}
+	return -1
+}
+
+// pd.Outlines() returns the outline tree of the PDF. On broken outlines the
+// entries found so far are returned, use pd.ReadOutlines() to get the
+// error.
+func (pd *PdfReaderT) Outlines() []Outline {
+	outlines, _ := pd.ReadOutlines()
+	return outlines
+}
+
+// pd.ReadOutlines() is like pd.Outlines() but reports broken outlines.
+func (pd *PdfReaderT) ReadOutlines() ([]Outline, error) {
+	d := pd.Dic(pd.Dic(pd.Trailer["/Root"])["/Outlines"])
+	if d == nil {
+		return nil, nil
+	}
+	return pd.outlines(d["/First"], make(map[int]bool), 0)
+}
+
+// pd.outlines() reads the outline items starting at first and their
+// children. done holds the items seen, against loops.
+func (pd *PdfReaderT) outlines(first []byte, done map[int]bool, depth int) ([]Outline, error) {
+	if depth > MAX_TREE_DEPTH {
+		return nil, parseError(pd.File, -1, ErrBadOutlines)
+	}
+	var outlines []Outline
+	for r := first; len(r) > 0; {
+		n, _ := refNums(r)
+		if n < 0 || done[n] {
+			return outlines, parseError(pd.File, -1, ErrBadOutlines)
+		}
+		done[n] = true
+		p := pd.Dic(r)
+		if p == nil {
+			return outlines, parseError(pd.File, -1, ErrBadOutlines)
+		}
+
+		o := Outline{
+			Title:  pd.Text(p["/Title"]),
+			Page:   -1,
+			Open:   pd.Num(p["/Count"]) > 0,
+			Italic: pd.Num(p["/F"])&1 != 0,
+			Bold:   pd.Num(p["/F"])&2 != 0,
+		}
+		if c := pd.Arr(p["/C"]); len(c) == 3 {
+			o.Color = []float64{pd.Float(c[0]), pd.Float(c[1]), pd.Float(c[2])}
+		}
+		if dest, ok := p["/Dest"]; ok {
+			o.Dest = pd.destination(dest)
+		} else if a, ok := p["/A"]; ok {
+			o.Action = pd.action(a)
+			if o.Action != nil && o.Action.Type == "/GoTo" {
+				o.Dest = o.Action.Dest
+			}
+		}
+		if o.Dest != nil {
+			o.Page, o.Ref = o.Dest.Page, o.Dest.Ref
+		}
+
+		var err error
+		if first, ok := p["/First"]; ok {
+			if o.Children, err = pd.outlines(first, done, depth+1); err != nil {
+				outlines = append(outlines, o)
+				return outlines, err
+			}
+		}
+		outlines = append(outlines, o)
+		r = p["/Next"]
+	}
+	return outlines, nil
+}
+
+// pd.destination() resolves a destination: an explicit one (an array), a
+// name looked up in the catalog /Dests, a string looked up in the /Dests
+// name tree, or a dictionary with /D. It returns nil if it can't be
+// resolved.
+func (pd *PdfReaderT) destination(d []byte) *Destination {
+	name := ""
+	for i := 0; i < 4; i++ {
+		d = pd.Obj(d)
+		switch {
+		case len(d) == 0:
+			return nil
+		case d[0] == '[':
+			dest := explicitDestination(pd, Array(d))
+			if dest != nil {
+				dest.Name = name
+			}
+			return dest
+		case d[0] == '/':
+			name = string(NormalizeName(d)[1:])
+			d = pd.Dic(pd.Dic(pd.Trailer["/Root"])["/Dests"])[string(d)]
+		case bytes.HasPrefix(d, []byte("<<")):
+			d = Dictionary(d)["/D"]
+		case d[0] == '(' || d[0] == '<':
+			key := ps.String(d)
+			name = DecodeText(key)
+			names := pd.Dic(pd.Dic(pd.Trailer["/Root"])["/Names"])
+			d, _ = pd.NameTree(names["/Dests"]).Get(string(key))
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// explicitDestination() parses [page /View params...]. With a nil reader
+// the page is a page number, as in destinations of remote files.
+func explicitDestination(pd *PdfReaderT, a [][]byte) *Destination {
+	if len(a) < 2 {
+		return nil
+	}
+	dest := &Destination{Page: -1, View: string(a[1])}
+	if o, _ := refNums(a[0]); o >= 0 && pd != nil {
+		dest.Ref = a[0]
+		dest.Page = pd.pageIndex(a[0])
+	} else if len(a[0]) > 0 && a[0][0] >= '0' && a[0][0] <= '9' {
+		dest.Page = num(a[0])
+	}
+	for _, v := range a[2:] {
+		f, ok := toFloat(parse(nil, v))
+		if !ok {
+			f = math.NaN()
+		}
+		dest.Params = append(dest.Params, f)
+	}
+	return dest
+}
+
+// pd.action() reads an action dictionary, nil if there is none.
+func (pd *PdfReaderT) action(a []byte) *Action {
+	d := pd.Dic(a)
+	if d == nil {
+		return nil
+	}
+	act := &Action{Type: string(pd.Obj(d["/S"])), Dict: d}
+	switch act.Type {
+	case "/GoTo":
+		act.Dest = pd.destination(d["/D"])
+	case "/GoToR", "/GoToE":
+		act.File = pd.fileSpec(d["/F"])
+		switch dest := pd.Obj(d["/D"]); {
+		case len(dest) > 0 && dest[0] == '[':
+			act.Dest = explicitDestination(nil, Array(dest))
+		case len(dest) > 0 && dest[0] == '/':
+			act.Dest = &Destination{Page: -1, Name: string(NormalizeName(dest)[1:])}
+		case len(dest) > 0:
+			act.Dest = &Destination{Page: -1, Name: DecodeText(ps.String(dest))}
+		}
+	case "/Launch":
+		act.File = pd.fileSpec(d["/F"])
+	case "/URI":
+		act.URI = string(ps.String(pd.Obj(d["/URI"])))
+	case "/Named":
+		act.Name = string(pd.Obj(d["/N"]))
+	}
+	return act
+}
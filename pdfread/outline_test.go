@@ -0,0 +1,95 @@
+package pdfread
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestOutlines reads the outlines of outline.pdf: explicit, named and
+// string destinations, actions, and a /Next loop at the last item.
+func TestOutlines(t *testing.T) {
+	pd, err := Open("testdata/outline.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	outlines, err := pd.ReadOutlines()
+	if !errors.Is(err, ErrBadOutlines) {
+		t.Errorf("err = %v, want %v", err, ErrBadOutlines)
+	}
+	if len(outlines) != 4 {
+		t.Fatalf("%d outlines", len(outlines))
+	}
+	if o := pd.Outlines(); len(o) != 4 || o[3].Title != "Remote" {
+		t.Errorf("Outlines() differs from ReadOutlines(): %+v", o)
+	}
+
+	o := outlines[0]
+	if o.Title != "Explicit" || o.Page != 1 || string(o.Ref) != "4 0 R" || !o.Open || !o.Italic || !o.Bold ||
+		!reflect.DeepEqual(o.Color, []float64{1, 0, 0}) || len(o.Children) != 2 {
+		t.Errorf("outline 0: %+v", o)
+	}
+	if p := o.Dest.Params; o.Dest.View != "/XYZ" || len(p) != 3 || p[0] != 10 || !math.IsNaN(p[1]) || p[2] != 2 {
+		t.Errorf("outline 0: destination %+v", o.Dest)
+	}
+	tests := []struct {
+		o          Outline
+		title      string
+		page       int
+		view, name string
+	}{
+		{o.Children[0], "Child named", 2, "/Fit", "chap2"},
+		{o.Children[1], "Child string", 2, "/FitBH", "sec.3"},
+		{o.Children[1].Children[0], "Grandchild GoTo", 0, "/FitR", "sec.1"},
+	}
+	for _, tt := range tests {
+		if tt.o.Title != tt.title || tt.o.Page != tt.page || tt.o.Dest == nil || tt.o.Dest.View != tt.view || tt.o.Dest.Name != tt.name {
+			t.Errorf("%s: %+v, destination %+v", tt.title, tt.o, tt.o.Dest)
+		}
+	}
+	if o.Children[1].Open || o.Children[1].Children[0].Action.Type != "/GoTo" {
+		t.Errorf("Child string: %+v", o.Children[1])
+	}
+
+	if o := outlines[1]; o.Title != "Über " || o.Action == nil || o.Action.Type != "/URI" || o.Action.URI != "http://example.com/" || o.Page != -1 {
+		t.Errorf("outline 1: %+v, action %+v", o, o.Action)
+	}
+	if o := outlines[2]; o.Action == nil || o.Action.Type != "/Named" || o.Action.Name != "/NextPage" {
+		t.Errorf("outline 2: %+v", o.Action)
+	}
+	a := outlines[3].Action
+	if a == nil || a.Type != "/GoToR" || a.File != "other.pdf" || a.Dest == nil || a.Dest.Page != 2 || a.Dest.Ref != nil || a.Dest.View != "/FitH" {
+		t.Errorf("outline 3: %+v", a)
+	}
+}
+
+// TestOutlineDepth nests outline items deeper than MAX_TREE_DEPTH.
+func TestOutlineDepth(t *testing.T) {
+	objs := map[int]string{
+		1: "<< /Type /Catalog /Pages 2 0 R /Outlines 3 0 R >>",
+		2: "<< /Type /Pages /Kids [] /Count 0 >>",
+		3: "<< /First 10 0 R >>",
+	}
+	n := MAX_TREE_DEPTH + 10
+	for i := 0; i < n; i++ {
+		objs[10+i] = fmt.Sprintf("<< /Title (%d) /First %d 0 R >>", i, 11+i)
+	}
+	pd, err := OpenBytes(makePDF(objs, "/Root 1 0 R"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outlines, err := pd.ReadOutlines()
+	if !errors.Is(err, ErrBadOutlines) {
+		t.Errorf("err = %v, want %v", err, ErrBadOutlines)
+	}
+	depth := 0
+	for ; len(outlines) == 1; outlines = outlines[0].Children {
+		depth++
+	}
+	if depth != MAX_TREE_DEPTH+1 {
+		t.Errorf("depth %d", depth)
+	}
+}
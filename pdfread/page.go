@@ -0,0 +1,155 @@
+package pdfread
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"strconv"
+)
+
+// Rectangle is a page box in default user space units, normalized so that
+// LLX <= URX and LLY <= URY.
+type Rectangle struct {
+	LLX, LLY, URX, URY float64
+}
+
+// r.Width() returns the width of the rectangle.
+func (r Rectangle) Width() float64 { return r.URX - r.LLX }
+
+// r.Height() returns the height of the rectangle.
+func (r Rectangle) Height() float64 { return r.URY - r.LLY }
+
+// r.Empty() reports whether the rectangle has no area.
+func (r Rectangle) Empty() bool { return r.LLX >= r.URX || r.LLY >= r.URY }
+
+// r.Intersect() returns the intersection of two rectangles, empty if they
+// don't overlap.
+func (r Rectangle) Intersect(s Rectangle) Rectangle {
+	return Rectangle{
+		LLX: math.Max(r.LLX, s.LLX), LLY: math.Max(r.LLY, s.LLY),
+		URX: math.Min(r.URX, s.URX), URY: math.Min(r.URY, s.URY),
+	}
+}
+
+// r.String() returns the rectangle as a PDF array.
+func (r Rectangle) String() string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	return "[" + f(r.LLX) + " " + f(r.LLY) + " " + f(r.URX) + " " + f(r.URY) + "]"
+}
+
+// letter is the media box of pages without one.
+var letter = Rectangle{0, 0, 612, 792}
+
+// Page is a page of the document with its attributes resolved, inherited
+// ones through /Parent as in pd.Attribute().
+type Page struct {
+	Index     int         // index in pd.Pages()
+	Ref       []byte      // reference of the page object
+	Dict      DictionaryT // the page dictionary
+	MediaBox  Rectangle   // letter size if missing
+	CropBox   Rectangle   // default MediaBox, clipped to it
+	BleedBox  Rectangle   // default CropBox, clipped to MediaBox
+	TrimBox   Rectangle   // default CropBox, clipped to MediaBox
+	ArtBox    Rectangle   // default CropBox, clipped to MediaBox
+	Rotate    int         // clockwise: 0, 90, 180 or 270
+	UserUnit  float64     // size of a user space unit in 1/72 inch, default 1
+	Resources DictionaryT // resource dictionary, nil if none
+	pd        *PdfReaderT
+}
+
+// pd.Page() returns page i (counting from 0, as in pd.Pages()), nil if
+// there is no such page.
+func (pd *PdfReaderT) Page(i int) *Page {
+	pages := pd.Pages()
+	if i < 0 || i >= len(pages) {
+		return nil
+	}
+	ref := pages[i]
+	d := pd.Dic(ref)
+	p := &Page{Index: i, Ref: ref, Dict: d, UserUnit: 1, pd: pd}
+
+	var ok bool
+	if p.MediaBox, ok = pd.rectangle(pd.Attribute("/MediaBox", ref)); !ok {
+		p.MediaBox = letter
+	}
+	p.CropBox = p.MediaBox
+	if r, ok := pd.rectangle(pd.Attribute("/CropBox", ref)); ok {
+		if r = r.Intersect(p.MediaBox); !r.Empty() {
+			p.CropBox = r
+		}
+	}
+	for _, b := range []struct {
+		name string
+		box  *Rectangle
+	}{
+		{"/BleedBox", &p.BleedBox},
+		{"/TrimBox", &p.TrimBox},
+		{"/ArtBox", &p.ArtBox},
+	} {
+		*b.box = p.CropBox
+		if r, ok := pd.rectangle(d[b.name]); ok {
+			if r = r.Intersect(p.MediaBox); !r.Empty() {
+				*b.box = r
+			}
+		}
+	}
+
+	if r := pd.Num(pd.Attribute("/Rotate", ref)) % 360; r%90 == 0 {
+		if r < 0 {
+			r += 360
+		}
+		p.Rotate = r
+	}
+	if u, ok := pd.float(d["/UserUnit"]); ok && u > 0 {
+		p.UserUnit = u
+	}
+	p.Resources = pd.Dic(pd.Attribute("/Resources", ref))
+	return p
+}
+
+// pd.rectangle() reads a rectangle [llx lly urx ury], false if it's
+// missing or malformed. Opposite corners may be given in any order.
+func (pd *PdfReaderT) rectangle(reference []byte) (Rectangle, bool) {
+	a := pd.Arr(reference)
+	if len(a) != 4 {
+		return Rectangle{}, false
+	}
+	var v [4]float64
+	for i := range a {
+		f, ok := pd.float(a[i])
+		if !ok {
+			return Rectangle{}, false
+		}
+		v[i] = f
+	}
+	return Rectangle{
+		LLX: math.Min(v[0], v[2]), LLY: math.Min(v[1], v[3]),
+		URX: math.Max(v[0], v[2]), URY: math.Max(v[1], v[3]),
+	}, true
+}
+
+// p.Contents() returns the decoded content streams of the page,
+// concatenated and separated by newlines. It's nil for a page without
+// contents.
+func (p *Page) Contents() ([]byte, error) {
+	if len(p.pd.Obj(p.Dict["/Contents"])) == 0 {
+		return nil, nil
+	}
+	var b bytes.Buffer
+	for i, ref := range p.pd.ForcedArray(p.Dict["/Contents"]) {
+		_, r, err := p.pd.StreamReader(ref)
+		if err != nil {
+			return b.Bytes(), err
+		}
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		b.Write(data)
+		if err != nil {
+			return b.Bytes(), parseError(p.pd.File, -1, err)
+		}
+	}
+	return b.Bytes(), nil
+}
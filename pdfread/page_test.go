@@ -0,0 +1,69 @@
+package pdfread
+
+import (
+	"testing"
+)
+
+func TestRectangle(t *testing.T) {
+	r := Rectangle{0, 0, 612, 792}
+	if r.Width() != 612 || r.Height() != 792 || r.Empty() || r.String() != "[0 0 612 792]" {
+		t.Errorf("%v: %v x %v", r, r.Width(), r.Height())
+	}
+	if s := r.Intersect(Rectangle{-10, 100, 300.5, 1000}); s != (Rectangle{0, 100, 300.5, 792}) {
+		t.Errorf("Intersect() = %v", s)
+	}
+	if s := r.Intersect(Rectangle{700, 0, 800, 10}); !s.Empty() {
+		t.Errorf("Intersect() = %v, not empty", s)
+	}
+}
+
+// TestPage reads the pages of page.pdf, with inherited attributes, boxes
+// outside of the media box and bad /Rotate values.
+func TestPage(t *testing.T) {
+	pd, err := Open("testdata/page.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	a4 := Rectangle{0, 0, 595, 842}
+	tests := []struct {
+		media, crop, bleed, trim, art Rectangle
+		rotate                        int
+		unit                          float64
+		resources                     string
+		contents                      string
+	}{
+		{a4, a4, Rectangle{0, 0, 5, 842}, Rectangle{10, 20, 500, 800}, a4, 270, 2.5, "/Font",
+			"BT /F1 12 Tf (Hello) Tj\nET 0 0 m 10 10 l S"},
+		{Rectangle{0, 0, 200, 100}, Rectangle{0, 0, 200, 100}, Rectangle{0, 0, 200, 100}, Rectangle{0, 0, 200, 100},
+			Rectangle{0, 0, 200, 100}, 90, 1, "/XObject", ""},
+		{letter, letter, letter, letter, letter, 0, 1, "/Font", "ET 0 0 m 10 10 l S"},
+	}
+	for i, tt := range tests {
+		p := pd.Page(i)
+		if p == nil {
+			t.Fatalf("no page %d", i)
+		}
+		if p.Index != i || string(p.Ref) != string(pd.Pages()[i]) {
+			t.Errorf("page %d: index %d, ref %s", i, p.Index, p.Ref)
+		}
+		if p.MediaBox != tt.media || p.CropBox != tt.crop || p.BleedBox != tt.bleed || p.TrimBox != tt.trim || p.ArtBox != tt.art {
+			t.Errorf("page %d: boxes %v %v %v %v %v", i, p.MediaBox, p.CropBox, p.BleedBox, p.TrimBox, p.ArtBox)
+		}
+		if p.Rotate != tt.rotate || p.UserUnit != tt.unit {
+			t.Errorf("page %d: /Rotate %d, /UserUnit %v", i, p.Rotate, p.UserUnit)
+		}
+		if _, ok := p.Resources[tt.resources]; !ok {
+			t.Errorf("page %d: resources %v", i, p.Resources)
+		}
+		c, err := p.Contents()
+		if err != nil || string(c) != tt.contents {
+			t.Errorf("page %d: contents %q, %v", i, c, err)
+		}
+	}
+	for _, i := range []int{-1, 3} {
+		if p := pd.Page(i); p != nil {
+			t.Errorf("Page(%d) = %+v", i, p)
+		}
+	}
+}
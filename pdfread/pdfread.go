@@ -10,13 +10,17 @@ package pdfread
 
 import (
 	"bytes"
-	"compress/zlib"
-	"encoding/ascii85"
-	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sync"
 
 	"github.com/raff/pdfreader/fancy"
-	"github.com/raff/pdfreader/lzw"
 	"github.com/raff/pdfreader/ps"
 	"github.com/raff/pdfreader/util"
 )
@@ -26,25 +30,63 @@ import (
 const (
 	MAX_PDF_UPDATES   = 1024
 	MAX_PDF_ARRAYSIZE = 1024
+	MAX_TREE_DEPTH    = 64      // of name, number, field and outline trees
+	MAX_LABEL_NUMBER  = 9999    // roman and letter page labels, decimal above
+	MAX_PRED_COLORS   = 32      // /Colors of a predictor
+	MAX_PRED_ROW      = 1 << 24 // bytes in a row of a predictor
 )
 
 // types
 
 type DictionaryT map[string][]byte
 
+// PdfReaderT is an opened PDF file. Its methods are safe for concurrent
+// use, except for pd.Close().
 type PdfReaderT struct {
-	File      string            // name of the file
-	Size      int64             // file size
-	Version   string            // PDF version
-	rdr       fancy.Reader      // reader for the contents
-	Startxref int               // starting of xref table
-	Xref      map[int]int       // "pointers" of the xref table
-	Trailer   DictionaryT       // trailer dictionary of the file
-	PageMode  string            // /Root/PageMode
-	rcache    map[string][]byte // resolver cache
-	rncache   map[string]int    // resolver cache (positions in file)
-	dicache   map[string]DictionaryT
-	pages     [][]byte // pages cache
+	File        string          // name of the file
+	Size        int64           // file size
+	Version     string          // PDF version
+	rdr         fancy.Reader    // reader used while opening the file
+	ra          io.ReaderAt     // contents for positional reads
+	blocks      *blockCacheT    // cached blocks of ra for pd.at()
+	closer      io.Closer       // closes the file, nil if owned by the caller
+	Startxref   int             // starting of xref table
+	Xref        map[int]int     // "pointers" of the xref table
+	XrefGen     map[int]int     // generation numbers of the objects in Xref
+	Compressed  map[int][2]int  // object stream and index of compressed objects
+	Trailer     DictionaryT     // trailer dictionary of the file
+	PageMode    string          // /Root/PageMode
+	Encrypted   bool            // file has an /Encrypt dictionary
+	Permissions int32           // /Encrypt/P - see the Perm* flags
+	rcache      *cacheT         // resolver cache (resolvedT)
+	dicache     *cacheT         // dictionary cache (DictionaryT)
+	objstms     *cacheT         // decoded object streams (*objStmT)
+	mu          sync.Mutex      // guards pages, pageNums, labels, revisions, lengths and warnings
+	pages       [][]byte        // pages cache
+	pageNums    map[int]int     // page index by object number, see pd.pageIndex()
+	labels      []string        // page labels cache
+	revisions   []Revision      // revisions cache
+	lengths     map[int64]int64 // recovered stream lengths by data position
+	warnings    []string        // problems found in the file, see pd.Warnings()
+	crypt       *cryptT         // security handler for encrypted files
+	files       fs.FS           // files of external streams, nil if they are disabled
+	root        *os.Root        // directory of the files with Options.ExternalFiles
+}
+
+// resolvedT is a resolved reference: data and position in the file.
+type resolvedT struct {
+	n int
+	s []byte
+}
+
+// Options holds settings for opening a PDF file.
+type Options struct {
+	Password      string // user or owner password of an encrypted file
+	Repair        bool   // try to read damaged files, see pd.Warnings()
+	CacheSize     int    // max number of cached objects, 0 for the default, -1 for no limit
+	ObjStmCache   int    // max number of cached object streams, 0 for the default, -1 for no limit
+	FS            fs.FS  // files of external streams (/F), nil to disable them
+	ExternalFiles bool   // with FS nil, read external streams from the directory of the PDF file
 }
 
 var _Bytes = []byte{}
@@ -61,6 +103,12 @@ func min(a, b int) int {
 	}
 	return b
 }
+func max64(a, b int64) int64 {
+	if a < b {
+		return b
+	}
+	return a
+}
 func end(a []byte, n int) int { return max(0, len(a)-n) }
 
 func num(n []byte) (r int) {
@@ -111,6 +159,15 @@ func refToken(f fancy.Reader) ([]byte, int64) {
 	return tok, p
 }
 
+// refNums() returns object and generation number of a reference.
+func refNums(s []byte) (o, g int) {
+	f := bytes.Fields(s)
+	if len(f) != 3 || string(f[2]) != "R" {
+		return -1, -1
+	}
+	return num(f[0]), num(f[1])
+}
+
 func tuple(f fancy.Reader, count int) [][]byte {
 	r := make([][]byte, count)
 	for i := 0; i < count; i++ {
@@ -135,6 +192,8 @@ func xrefStart(f fancy.Reader) int {
 }
 
 // xrefSkip() queries the start of the trailer for a (partial) xref-table.
+// The entries are read as tokens, so entries that are not exactly 20 bytes
+// long are tolerated.
 func xrefSkip(f fancy.Reader, xref int) int {
 	f.Seek(int64(xref), 0)
 	t, p := ps.Token(f)
@@ -143,18 +202,34 @@ func xrefSkip(f fancy.Reader, xref int) int {
 	}
 	for {
 		t, p = ps.Token(f)
-		if t[0] < '0' || t[0] > '9' {
+		if len(t) == 0 || t[0] < '0' || t[0] > '9' {
 			f.Seek(p, 0)
 			break
 		}
 		t, _ = ps.Token(f)
-		ps.SkipLE(f)
-		f.Seek(int64(num(t)*20), 1)
+		if !xrefEntries(f, num(t), nil) {
+			return -1
+		}
 	}
 	r, _ := f.Seek(0, 1)
 	return int(r)
 }
 
+// xrefEntries() reads n entries of a xref-table subsection, calling fn
+// for each entry if not nil.
+func xrefEntries(f fancy.Reader, n int, fn func(i, offs, gen int, used bool)) bool {
+	for i := 0; i < n; i++ {
+		e := tuple(f, 3)
+		if len(e[2]) != 1 || (e[2][0] != 'n' && e[2][0] != 'f') {
+			return false
+		}
+		if fn != nil {
+			fn(i, num(e[0]), num(e[1]), e[2][0] == 'n')
+		}
+	}
+	return true
+}
+
 // Dictionary() makes a map/hash from PDF dictionary data.
 func Dictionary(s []byte) DictionaryT {
 	if len(s) < 4 {
@@ -209,176 +284,299 @@ func ForcedArray(s []byte) [][]byte {
 	return Array(s)
 }
 
-// xrefReadTable() reads the xref table(s) of a PDF file. This is not recursive
-// in favour of not to have to keep track of already used starting points
-// for xrefs.
-func xrefReadTable(f fancy.Reader, p int) (map[int]int, DictionaryT) {
-	var trailer DictionaryT
-	var back [MAX_PDF_UPDATES]int
-	b := 0
-	s := _Bytes
-	for ok := true; ok; {
-		back[b] = p
-		b++
-		p = xrefSkip(f, p)
+// xrefSectionT is one section of the xref: a table or a stream.
+type xrefSectionT struct {
+	pos     int         // position of the section
+	stream  bool        // xref stream instead of table
+	xrefstm int         // /XRefStm of a table in a hybrid file, -1 if none
+	trailer DictionaryT // trailer (or stream) dictionary
+}
+
+// xrefChain() follows the /Prev chain of the xref sections starting at p.
+// This is not recursive in favour of not to have to keep track of already
+// used starting points for xrefs. The sections are returned newest first.
+func xrefChain(f fancy.Reader, p int) ([]xrefSectionT, error) {
+	var r []xrefSectionT
+	done := make(map[int]bool)
+	for {
+		if done[p] || len(r) == MAX_PDF_UPDATES {
+			util.Log("xref /Prev loop at", p)
+			return nil, parseError("", int64(p), ErrBadXref)
+		}
+		done[p] = true
+
+		sec := xrefSectionT{pos: p, xrefstm: -1}
 		f.Seek(int64(p), 0)
-		s, _ = ps.Token(f)
-		if string(s) != "trailer" {
-			util.Log("no trailer / xref table")
-			return nil, nil
+		if t, _ := ps.Token(f); string(t) == "xref" {
+			q := xrefSkip(f, p)
+			if q < 0 {
+				util.Log("bad xref table")
+				return nil, parseError("", int64(p), ErrBadXref)
+			}
+			f.Seek(int64(q), 0)
+			if t, _ = ps.Token(f); string(t) != "trailer" {
+				util.Log("no trailer / xref table")
+				return nil, parseError("", int64(q), ErrNoTrailer)
+			}
+			t, _ = ps.Token(f)
+			if sec.trailer = Dictionary(t); sec.trailer == nil {
+				return nil, parseError("", int64(q), ErrNoTrailer)
+			}
+			if s, ok := sec.trailer["/XRefStm"]; ok {
+				sec.xrefstm = num(s)
+			}
+		} else {
+			dic, err := xrefStreamSection(f, p, nil)
+			if err != nil {
+				return nil, err
+			}
+			sec.stream = true
+			sec.trailer = dic
 		}
-		s, _ = ps.Token(f)
-		if trailer == nil {
-			trailer = Dictionary(s)
+		r = append(r, sec)
+
+		s, ok := sec.trailer["/Prev"]
+		if !ok {
+			return r, nil
 		}
-		s, ok = Dictionary(s)["/Prev"]
 		p = num(s)
 	}
-	r := make(map[int]int)
-	for b != 0 {
-		b--
-		f.Seek(int64(back[b]), 0)
-		ps.Token(f) // skip "xref"
-		for {
-			m := tuple(f, 2)
-			if string(m[0]) == "trailer" {
-				break
-			}
-			ps.SkipLE(f)
-			o := num(m[0])
-			dat := f.Slice(num(m[1]) * 20)
-			for i := 0; i < len(dat); i += 20 {
-				if dat[i+17] != 'n' {
-					delete(r, o)
-				} else {
-					r[o] = num(dat[i : i+10])
-				}
-				o++
-			}
+}
+
+// xrefT is the xref of a PDF file.
+type xrefT struct {
+	offs map[int]int    // positions of the regular objects
+	gen  map[int]int    // generation numbers of the regular objects
+	c    map[int][2]int // object stream and index of compressed objects
+}
+
+func newXref() *xrefT {
+	return &xrefT{make(map[int]int), make(map[int]int), make(map[int][2]int)}
+}
+
+// xrefRead() reads the xref of a PDF file: all sections of the /Prev chain
+// are applied oldest first, so newer sections win.
+func xrefRead(f fancy.Reader, p int) (*xrefT, DictionaryT, error) {
+	secs, err := xrefChain(f, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	x := newXref()
+	for b := len(secs) - 1; b >= 0; b-- {
+		if err := xrefApply(f, secs[b], x); err != nil {
+			return nil, nil, err
 		}
 	}
-	return r, trailer
+	return x, secs[0].trailer, nil
 }
 
-// xrefReadStream() reads the xref stream(s) of a PDF file. This is not recursive
-// in favour of not to have to keep track of already used starting points
-// for xrefs.
-func xrefReadStream(f fancy.Reader, p int) (xr map[int]int, r [][2]int, trailer DictionaryT) {
-	s := _Bytes
-
-	xr = map[int]int{}
-	r = [][2]int{}
+// xrefApply() applies the entries of one xref section to the xref x.
+func xrefApply(f fancy.Reader, sec xrefSectionT, x *xrefT) error {
+	// used is nil for stream sections; for hybrid sections it holds the
+	// objects in use in the table, the table wins over its stream
+	var used map[int]bool
+	apply := func(o, typ, f2, f3 int) {
+		if used[o] {
+			return
+		}
+		switch typ {
+		case 0: // free object (the table is authoritative for hybrid files)
+			if used == nil {
+				x.free(o)
+			}
+		case 1: // regular object
+			x.set(o, f2, f3)
+		case 2: // compressed object
+			x.free(o)
+			x.c[o] = [2]int{f2, f3}
+		}
+	}
 
-	for ok := true; ok; {
-		f.Seek(int64(p), 0)
-		ps.Token(f) // skip "xref"
+	if sec.stream {
+		_, err := xrefStreamSection(f, sec.pos, apply)
+		return err
+	}
 
-		//for {
+	used = make(map[int]bool)
+	f.Seek(int64(sec.pos), 0)
+	ps.Token(f) // skip "xref"
+	for {
 		m := tuple(f, 2)
-		if string(m[1]) != "obj" {
-			util.Logf("unexpected %q\n", m)
-			return nil, nil, nil
+		if string(m[0]) == "trailer" || len(m[0]) == 0 {
+			break
 		}
+		o := num(m[0])
+		xrefEntries(f, num(m[1]), func(i, offs, gen int, inuse bool) {
+			if !inuse {
+				x.free(o + i)
+			} else {
+				x.set(o+i, offs, gen)
+				used[o+i] = true
+			}
+		})
+	}
 
-		s, _ = ps.Token(f)
-		dic := Dictionary(s)
+	// the objects of the hybrid section's stream that are not in use
+	// in the table (they are usually marked free there)
+	if sec.xrefstm >= 0 {
+		_, err := xrefStreamSection(f, sec.xrefstm, apply)
+		return err
+	}
+	return nil
+}
 
-		if trailer == nil {
-			trailer = dic
-		}
+// x.clone() returns a copy of x.
+func (x *xrefT) clone() *xrefT {
+	r := newXref()
+	for o, p := range x.offs {
+		r.offs[o] = p
+	}
+	for o, g := range x.gen {
+		r.gen[o] = g
+	}
+	for o, c := range x.c {
+		r.c[o] = c
+	}
+	return r
+}
 
-		s, ok = dic["/Prev"]
-		p = num(s)
+// x.set() records the regular object o.
+func (x *xrefT) set(o, offs, gen int) {
+	x.offs[o] = offs
+	x.gen[o] = gen
+	delete(x.c, o)
+}
 
-		s, _ = ps.Token(f)
-		if string(s) != "stream" {
-			util.Log("not a stream", s)
-			return nil, nil, nil
-		}
-		ps.SkipLE(f)
+// x.free() removes object o.
+func (x *xrefT) free(o int) {
+	delete(x.offs, o)
+	delete(x.gen, o)
+	delete(x.c, o)
+}
 
-		for k, v := range dic {
-			util.Logf("%s %s", k, v)
-		}
+// xrefStreamSection() reads the xref stream at position p and calls fn for
+// each entry with object number, type and the two other fields. With fn
+// nil only the dictionary is read.
+func xrefStreamSection(f fancy.Reader, p int, fn func(o, typ, f2, f3 int)) (DictionaryT, error) {
+	f.Seek(int64(p), 0)
+	ps.Token(f) // skip "xref"
+
+	m := tuple(f, 2)
+	if string(m[1]) != "obj" {
+		util.Logf("unexpected %q\n", m)
+		return nil, parseError("", int64(p), ErrBadXref)
+	}
 
-		size := num(dic["/Size"])
-		index := []int{0, size}
+	s, _ := ps.Token(f)
+	dic := Dictionary(s)
 
-		if _, ok := dic["/Index"]; ok {
-			a := Array(dic["/Index"])
-			index[0] = num(a[0])
-			index[1] = num(a[1])
+	s, _ = ps.Token(f)
+	if string(s) != "stream" || dic == nil || string(dic["/Type"]) != "/XRef" {
+		util.Log("not a xref stream", s)
+		return nil, parseError("", int64(p), ErrBadXref)
+	}
+	ps.SkipLE(f)
 
-			// can len(index) be != 2 ?
-		}
+	w := Array(dic["/W"])
+	if len(w) != 3 {
+		util.Log("unexpected /W", w)
+		return nil, parseError("", int64(p), ErrBadXref)
+	}
 
-		l := num(dic["/Length"])
-		xref := f.Slice(l)
+	if fn == nil {
+		return dic, nil
+	}
 
-		w := Array(dic["/W"])
-		if len(w) != 3 {
-			util.Log("unexpected /W", w)
-		}
+	for k, v := range dic {
+		util.Logf("%s %s", k, v)
+	}
 
-		fl1 := num(w[0])
-		fl2 := num(w[1])
-		fl3 := num(w[2])
+	// /Index has pairs of first object number and count, one per subsection
+	index := []int{0, num(dic["/Size"])}
+	if a := Array(dic["/Index"]); a != nil {
+		index = make([]int, len(a)&^1)
+		for i := range index {
+			index[i] = num(a[i])
+		}
+	}
 
-		width := fl1 + fl2 + fl3
+	l := num(dic["/Length"])
+	xref := f.Slice(l)
 
-		xref = decodeStream(dic, xref)
+	fl1 := num(w[0])
+	fl2 := num(w[1])
+	fl3 := num(w[2])
 
-		s, _ = ps.Token(f) // endstream
-		s, _ = ps.Token(f) // endobj
+	width := fl1 + fl2 + fl3
 
-		pos := index[0]
+	xref, _ = decodeStream(dic, xref)
 
-		for i := 0; i < len(xref); i += width {
+	i := 0
+	for k := 0; k < len(index); k += 2 {
+		for pos, n := index[k], index[k+1]; n > 0 && width > 0 && i+width <= len(xref); n-- {
 			ent := xref[i : i+width]
-			f1 := bnum(ent[0:fl1])
+			f1 := 1 // type 1 if the field is missing
+			if fl1 > 0 {
+				f1 = bnum(ent[0:fl1])
+			}
 			f2 := bnum(ent[fl1 : fl1+fl2])
 			f3 := bnum(ent[fl1+fl2:])
 
-			switch f1 {
-			case 0:
-				// free object
-				util.Log("free", f2, f3)
-				// delete(r, f2)
-
-			case 1:
-				// regular object
-				util.Log("ref", pos, f3, f2)
-				xr[pos] = f2
-
-			case 2:
-				// compressed object
-				util.Log("cref", pos, f2, f3)
-				r = append(r, [2]int{f2, f3})
-			}
+			util.Log("xref", pos, f1, f2, f3)
+			fn(pos, f1, f2, f3)
 
-			pos += 1
+			pos++
+			i += width
 		}
-		//}
 	}
 
-	return xr, r, trailer
+	return dic, nil
+}
+
+// pd.ref() returns a reference to object o with its generation number.
+func (pd *PdfReaderT) ref(o int) []byte {
+	return []byte(fmt.Sprintf("%d %d R", o, pd.XrefGen[o]))
+}
+
+// pd.at() returns a reader positioned at p. Each call gets its own reader,
+// so concurrent reads don't share a position; the data comes from the
+// shared pd.blocks.
+func (pd *PdfReaderT) at(p int) fancy.Reader {
+	f := fancy.SecReader(pd.blocks, pd.Size)
+	f.Seek(int64(p), 0)
+	return f
 }
 
 // object() extracts the top informations of a PDF "object". For streams
 // this would be the dictionary as bytes.  It also returns the position in
 // binary data where one has to continue to read for this "object".
-func (pd *PdfReaderT) object(o int) (int, []byte) {
+// Free objects and objects of another generation are null (no data).
+func (pd *PdfReaderT) object(o, g int) (int, []byte) {
 	p, ok := pd.Xref[o]
 	if !ok {
+		if g != 0 {
+			return -1, _Bytes
+		}
+		if r := pd.compressed(o); r != nil {
+			return -1, r
+		}
+		return -1, _Bytes
+	}
+	if xg, ok := pd.XrefGen[o]; ok && xg != g {
+		util.Log("object", o, "has generation", xg, "not", g)
 		return -1, _Bytes
 	}
-	pd.rdr.Seek(int64(p), 0)
-	m := tuple(pd.rdr, 3)
-	if num(m[0]) != o {
+	f := pd.at(p)
+	m := tuple(f, 3)
+	if num(m[0]) != o || num(m[1]) != g {
 		return -1, _Bytes
 	}
-	r, np := refToken(pd.rdr)
-	return int(np) + len(r), r
+	r, np := refToken(f)
+	n := int(np) + len(r)
+	if pd.crypt != nil {
+		r = pd.crypt.decryptStrings(r, o, g)
+	}
+	return n, r
 }
 
 // pd.Resolve() resolves a reference in the PDF file. You'll probably need
@@ -392,22 +590,20 @@ func (pd *PdfReaderT) Resolve(s []byte) (int, []byte) {
 	resolve = func(s []byte) (int, []byte) {
 		n := -1
 		if len(s) >= 5 && s[0] >= '0' && s[0] <= '9' && s[len(s)-1] == 'R' {
-			z, ok := pd.rcache[string(s)]
-			if ok {
-				return pd.rncache[string(s)], z
+			if z, ok := pd.rcache.get(string(s)); ok {
+				return z.(resolvedT).n, z.(resolvedT).s
 			}
 			orig := s
-			o := num(s)
-			if _, ok = done[o]; ok {
+			o, g := refNums(s)
+			if _, ok := done[o]; ok {
 				return -1, _Bytes
 			}
 			done[o] = 1
-			n, s = pd.object(o)
+			n, s = pd.object(o, g)
 			if len(s) >= 5 && s[0] >= '0' && s[0] <= '9' && s[len(s)-1] == 'R' {
 				n, s = resolve(s)
 			}
-			pd.rcache[string(orig)] = s
-			pd.rncache[string(orig)] = n
+			pd.rcache.put(string(orig), resolvedT{n, s})
 		}
 		return n, s
 	}
@@ -427,13 +623,24 @@ func (pd *PdfReaderT) Num(reference []byte) int {
 	return num(pd.Obj(reference))
 }
 
+// pd.Float() queries real data from a reference.
+func (pd *PdfReaderT) Float(reference []byte) float64 {
+	f, _ := pd.float(reference)
+	return f
+}
+
+// pd.float() is pd.Float(), false if the data isn't a number.
+func (pd *PdfReaderT) float(reference []byte) (float64, bool) {
+	return toFloat(parse(nil, pd.Obj(reference)))
+}
+
 // pd.Dic() queries dictionary data from a reference.
 func (pd *PdfReaderT) Dic(reference []byte) DictionaryT {
-	d, ok := pd.dicache[string(reference)]
-	if !ok {
-		d = Dictionary(pd.Obj(reference))
-		pd.dicache[string(reference)] = d
+	if d, ok := pd.dicache.get(string(reference)); ok {
+		return d.(DictionaryT)
 	}
+	d := Dictionary(pd.Obj(reference))
+	pd.dicache.put(string(reference), d)
 	return d
 }
 
@@ -453,67 +660,57 @@ func (pd *PdfReaderT) ForcedArray(reference []byte) [][]byte {
 }
 
 // pd.Pages() returns an array with references to the pages of the PDF.
+// On a broken page tree the pages found so far are returned, use
+// pd.ReadPages() to get the error.
 func (pd *PdfReaderT) Pages() [][]byte {
-	if pd.pages != nil {
-		return pd.pages
+	pages, _ := pd.ReadPages()
+	return pages
+}
+
+// pd.ReadPages() is like pd.Pages() but reports a broken page tree.
+func (pd *PdfReaderT) ReadPages() ([][]byte, error) {
+	pd.mu.Lock()
+	cached := pd.pages
+	pd.mu.Unlock()
+	if cached != nil {
+		return cached, nil
 	}
 	pages := pd.Dic(pd.Dic(pd.Trailer["/Root"])["/Pages"])
-	pd.pages = make([][]byte, pd.Num(pages["/Count"]))
-	cp := 0
+	if pages == nil {
+		return nil, parseError(pd.File, -1, ErrBadPageTree)
+	}
+	r := make([][]byte, 0, max(0, min(pd.Num(pages["/Count"]), len(pd.Xref)+len(pd.Compressed))))
 	done := make(map[string]int)
-	var q func(p [][]byte)
-	q = func(p [][]byte) {
+	var q func(p [][]byte) error
+	q = func(p [][]byte) error {
 		for k := range p {
-			if _, wrong := done[string(p[k])]; !wrong {
-				done[string(p[k])] = 1
-				if kids, ok := pd.Dic(p[k])["/Kids"]; ok {
-					q(pd.Arr(kids))
-				} else {
-					pd.pages[cp] = p[k]
-					cp++
+			if _, wrong := done[string(p[k])]; wrong {
+				util.Log("Bad Page-Tree!", string(p[k]))
+				return parseError(pd.File, -1, ErrBadPageTree)
+			}
+			done[string(p[k])] = 1
+			d := pd.Dic(p[k])
+			if d == nil {
+				util.Log("Bad Page-Tree!", string(p[k]))
+				return parseError(pd.File, -1, ErrBadPageTree)
+			}
+			if kids, ok := d["/Kids"]; ok {
+				if err := q(pd.Arr(kids)); err != nil {
+					return err
 				}
 			} else {
-				panic("Bad Page-Tree!")
+				r = append(r, p[k])
 			}
 		}
-	}
-	q(pd.Arr(pages["/Kids"]))
-	return pd.pages
-}
-
-type Outline struct {
-	Title string
-	Page  int
-	Ref   []byte
-}
-
-func (pd *PdfReaderT) pageNum(p []byte) int {
-	for i, pg := range pd.Pages() {
-		if bytes.Equal(p, pg) {
-			return i
-		}
-	}
-
-	return -1
-}
-
-func (pd *PdfReaderT) Outlines() []Outline {
-	if pd.PageMode != "/UseOutlines" {
 		return nil
 	}
-
-	d := pd.Dic(pd.Dic(pd.Trailer["/Root"])["/Outlines"])
-	outlines := make([]Outline, pd.Num(d["/Count"]))
-
-	p := pd.Dic(d["/First"])
-	for i := 0; p != nil; i++ {
-		outlines[i].Ref = pd.Arr(p["/Dest"])[0]
-		outlines[i].Page = pd.pageNum(outlines[i].Ref)
-		outlines[i].Title = string(ps.String(p["/Title"]))
-		p = pd.Dic(p["/Next"])
+	if err := q(pd.Arr(pages["/Kids"])); err != nil {
+		return r, err
 	}
-
-	return outlines
+	pd.mu.Lock()
+	pd.pages = r
+	pd.mu.Unlock()
+	return r, nil
 }
 
 // pd.Attribute() tries to get an attribute definition from a page
@@ -541,92 +738,140 @@ func (pd *PdfReaderT) Att(a string, src []byte) []byte {
 	return pd.Obj(pd.Attribute(a, src))
 }
 
-// pd.Stream() returns contents of a stream.
+// pd.Stream() returns contents of a stream. The data of an external
+// stream is read from its file, if Options.FS or Options.ExternalFiles
+// allow it, see pd.StreamFile().
 func (pd *PdfReaderT) Stream(reference []byte) (DictionaryT, []byte) {
-	q, d := pd.Resolve(reference)
-	dic := pd.Dic(d)
-	l := pd.Num(dic["/Length"])
-	pd.rdr.Seek(int64(q), 0)
-	t, _ := ps.Token(pd.rdr)
-	if string(t) != "stream" {
-		util.Log("Stream", string(reference), "not a stream", string(t))
-		return nil, []byte{}
+	dic, r, err := pd.rawStream(reference)
+	if err != nil {
+		util.Log("Stream", string(reference), err)
+		return dic, []byte{}
 	}
-	ps.SkipLE(pd.rdr)
-	return dic, pd.rdr.Slice(l)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		util.Log("Stream", string(reference), err)
+	}
+	return dic, data
 }
 
-// pd.DecodedStream() returns decoded contents of a stream.
-func (pd *PdfReaderT) DecodedStream(reference []byte) (DictionaryT, []byte) {
-	dic, data := pd.Stream(reference)
-	return dic, decodeStream(dic, data)
+// pd.rawStream() returns the dictionary of a stream and a reader for the
+// decrypted data, without applying filters.
+func (pd *PdfReaderT) rawStream(reference []byte) (DictionaryT, io.ReadCloser, error) {
+	dic, start, l, err := pd.streamData(reference)
+	if err != nil {
+		return nil, nil, err
+	}
+	if name := pd.StreamFile(reference); name != "" {
+		// external files are not encrypted
+		f, err := pd.openFile(name)
+		if err != nil {
+			return dic, nil, parseError(pd.File, start, fmt.Errorf("%w %s: %w", ErrExternalStream, name, err))
+		}
+		return dic, f, nil
+	}
+	var r io.Reader = io.NewSectionReader(pd.ra, start, l)
+	if pd.crypt != nil {
+		o, g := refNums(reference)
+		r = pd.crypt.decryptReader(pd.streamCrypt(dic), o, g, r)
+	}
+	return dic, ioutil.NopCloser(r), nil
 }
 
-func decodeStream(dic DictionaryT, data []byte) []byte {
-	if f, ok := dic["/Filter"]; ok {
-		filter := ForcedArray(f)
-		var decos [][]byte
-		if d, ok := dic["/DecodeParms"]; ok {
-			decos = ForcedArray(d)
-		} else {
-			decos = make([][]byte, len(filter))
-		}
-
-		for ff := range filter {
-			// XXX: if there are multiple filters but only one DecodeParams,
-			//      it should be used for all filters
-
-			deco := Dictionary(decos[ff])
-			switch string(filter[ff]) {
-			case "/FlateDecode":
-				data = fancy.ReadAndClose(zlib.NewReader(fancy.SliceReader(data)))
-			case "/LZWDecode":
-				early := true
-				if deco != nil {
-					if s, ok := deco["/EarlyChange"]; ok {
-						early = num(s) == 1
-					}
-				}
-				data = lzw.Decode(data, early)
-			case "/ASCII85Decode":
-				ds := data
-				for len(ds) > 1 && ds[len(ds)-1] < 33 {
-					ds = ds[0 : len(ds)-1]
-				}
-				if len(ds) >= 2 && ds[len(ds)-1] == '>' && ds[len(ds)-2] == '~' {
-					ds = ds[0 : len(ds)-2]
-				}
-				data = fancy.ReadAll(ascii85.NewDecoder(fancy.SliceReader(ds)))
-			case "/ASCIIHexDecode":
-				data, _ = hex.DecodeString(string(data))
-			default:
-				util.Log("Unsupported filter", string(filter[ff]))
-				data = []byte{}
-			}
+// pd.streamData() returns the dictionary of a stream and the position and
+// length of its data. A /Length not followed by endstream (wrong, or an
+// object that's missing) is replaced by the position of the endstream
+// keyword, with a warning.
+func (pd *PdfReaderT) streamData(reference []byte) (DictionaryT, int64, int64, error) {
+	q, d := pd.Resolve(reference)
+	if q < 0 {
+		return nil, 0, 0, parseError(pd.File, -1, ErrNotStream)
+	}
+	dic := pd.Dic(d)
+	f := pd.at(q)
+	if t, _ := ps.Token(f); string(t) != "stream" {
+		return nil, 0, 0, parseError(pd.File, int64(q), ErrNotStream)
+	}
+	ps.SkipLE(f)
+	start, _ := f.Seek(0, 1)
+	if _, ok := dic["/F"]; ok {
+		// the data is in an external file, /Length is ignored
+		return dic, start, 0, nil
+	}
+
+	l, ok := toInt(parse(nil, pd.streamObj(dic, dic["/Length"])))
+	if ok && l >= 0 && pd.endstreamAt(start+int64(l)) {
+		return dic, start, int64(l), nil
+	}
+	pd.mu.Lock()
+	n, found := pd.lengths[start]
+	pd.mu.Unlock()
+	if found {
+		return dic, start, n, nil
+	}
 
-			if s, ok := deco["/Predictor"]; ok {
-				pred := num(s)
+	bad := "missing /Length"
+	if ok {
+		bad = fmt.Sprintf("/Length %d", l)
+	}
+	n = pd.findEndstream(start)
+	if n < 0 {
+		n = pd.Size - start
+		if ok && l >= 0 && int64(l) < n {
+			n = int64(l)
+		}
+		pd.warn("stream %s: %s, no endstream found", reference, bad)
+	} else {
+		pd.warn("stream %s: %s, endstream after %d bytes", reference, bad, n)
+	}
+	pd.mu.Lock()
+	if pd.lengths == nil {
+		pd.lengths = make(map[int64]int64)
+	}
+	pd.lengths[start] = n
+	pd.mu.Unlock()
+	return dic, start, n, nil
+}
 
-				switch {
-				case pred == 1:
-					// no predictor
+// pd.StreamReader() returns a reader for the decoded contents of a stream.
+// The data is read from the file and decoded while it's read, so it doesn't
+// have to fit in memory. Image codecs are not decoded, as with
+// pd.DecodedStream(). The reader must be closed: this closes the file of an
+// external stream, not the PDF file.
+func (pd *PdfReaderT) StreamReader(reference []byte) (DictionaryT, io.ReadCloser, error) {
+	dic, raw, err := pd.rawStream(reference)
+	if err != nil {
+		return dic, nil, err
+	}
+	r, _, err := decodeReader(pd.filterDic(dic), raw)
+	if err != nil {
+		raw.Close()
+		return dic, nil, parseError(pd.File, -1, err)
+	}
+	return dic, readCloserT{r, raw}, nil
+}
 
-				case pred > 10:
-					colors := numdef(deco["/Colors"], 1)
-					columns := numdef(deco["/Columns"], 1)
-					bitspercomponent := numdef(deco["/BitsPerComponent"], 8)
+// readCloserT reads decoded data and closes the raw stream.
+type readCloserT struct {
+	io.Reader
+	io.Closer
+}
 
-					util.Log("applying predictor", pred, colors, columns, bitspercomponent)
-					data = util.ApplyPNGPredictor(pred, colors, columns, bitspercomponent, data)
+// pd.DecodedStream() returns decoded contents of a stream. Image codecs
+// are not decoded, see pd.DecodedStreamFilter().
+func (pd *PdfReaderT) DecodedStream(reference []byte) (DictionaryT, []byte) {
+	dic, data, _ := pd.DecodedStreamFilter(reference)
+	return dic, data
+}
 
-				default:
-					util.Log("Unsupported predictor", pred)
-					return nil
-				}
-			}
-		}
-	}
-	return data
+// pd.DecodedStreamFilter() returns the contents of a stream decoded up to
+// the first image codec (/DCTDecode, /JPXDecode or /JBIG2Decode) and that
+// filter, which is left to the caller. The filter is "" if the data is
+// fully decoded.
+func (pd *PdfReaderT) DecodedStreamFilter(reference []byte) (DictionaryT, []byte, string) {
+	dic, data := pd.Stream(reference)
+	data, filter := decodeStream(pd.filterDic(dic), data)
+	return dic, data, filter
 }
 
 // pd.PageFonts() returns references to the fonts defined for a page.
@@ -640,139 +885,225 @@ func (pd *PdfReaderT) PageFonts(page []byte) DictionaryT {
 
 // pd.Close() closes the underlying file and releases all resources
 func (pd *PdfReaderT) Close() {
-	pd.rdr.Close()
+	if pd.closer != nil {
+		pd.closer.Close()
+	}
 	pd.File = ""
 	pd.Version = ""
 	pd.rdr = nil
+	pd.ra = nil
+	pd.blocks = nil
+	pd.closer = nil
 	pd.Startxref = -1
 	pd.Xref = nil
+	pd.XrefGen = nil
+	pd.Compressed = nil
 	pd.Trailer = nil
 	pd.PageMode = ""
 	pd.rcache = nil
-	pd.rncache = nil
 	pd.dicache = nil
 	pd.pages = nil
+	pd.pageNums = nil
+	pd.labels = nil
+	pd.revisions = nil
+	pd.lengths = nil
+	pd.warnings = nil
+	pd.crypt = nil
+	pd.files = nil
+	if pd.root != nil {
+		pd.root.Close()
+	}
+	pd.root = nil
+	pd.objstms = nil
 }
 
-// Load() loads a PDF file of a given name.
+// Load() loads a PDF file of a given name. Errors are only logged, use
+// Open() to get them.
 func Load(fn string) *PdfReaderT {
-	return load(fn, fancy.FileReader(fn))
+	pd, err := Open(fn)
+	if err != nil {
+		util.Log(err)
+		return nil
+	}
+	return pd
 }
 
-// LoadBytes() loads a PDF file from a byte buffer.
+// LoadBytes() loads a PDF file from a byte buffer. Errors are only logged,
+// use OpenBytes() to get them.
 func LoadBytes(b []byte) *PdfReaderT {
-	return load("<buffer>", fancy.BytesReader(b))
+	pd, err := OpenBytes(b)
+	if err != nil {
+		util.Log(err)
+		return nil
+	}
+	return pd
 }
 
-func load(fn string, fr fancy.Reader) *PdfReaderT {
-	var rr [][2]int // list of entries to resolve
+// Open() opens a PDF file of a given name. Encrypted files are opened
+// with the empty password.
+func Open(fn string) (*PdfReaderT, error) {
+	return OpenOptions(fn, nil)
+}
+
+// OpenOptions() opens a PDF file of a given name with options.
+func OpenOptions(fn string, opt *Options) (*PdfReaderT, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	pd, err := open(fn, f, f, st.Size(), opt)
+	if pd != nil && opt != nil && opt.FS == nil && opt.ExternalFiles {
+		// unlike os.DirFS(), os.Root doesn't follow links out of the directory
+		if root, err := os.OpenRoot(filepath.Dir(fn)); err != nil {
+			util.Log("external files", err)
+		} else {
+			pd.root = root
+			pd.files = root.FS()
+		}
+	}
+	return pd, err
+}
+
+// OpenBytes() opens a PDF file from a byte buffer.
+func OpenBytes(b []byte) (*PdfReaderT, error) {
+	return open("<buffer>", bytes.NewReader(b), nil, int64(len(b)), nil)
+}
+
+// NewReader() opens a PDF file from any io.ReaderAt of the given size. The
+// name is only used for reporting. The caller keeps ownership of r:
+// pd.Close() does not close it. r must allow concurrent ReadAt() calls for
+// concurrent use of the PdfReaderT.
+func NewReader(r io.ReaderAt, size int64, name string) (*PdfReaderT, error) {
+	return NewReaderOptions(r, size, name, nil)
+}
+
+// NewReaderOptions() is NewReader() with options.
+func NewReaderOptions(r io.ReaderAt, size int64, name string, opt *Options) (*PdfReaderT, error) {
+	return open(name, r, nil, size, opt)
+}
+
+func open(fn string, ra io.ReaderAt, closer io.Closer, size int64, opt *Options) (*PdfReaderT, error) {
+	var err error
+
+	if opt == nil {
+		opt = &Options{}
+	}
 
 	r := new(PdfReaderT)
 	r.File = fn
-	r.rdr = fr
-	if r.rdr == nil {
-		util.Log(fn, "FileReader error")
-		return nil
+	r.ra = ra
+	r.closer = closer
+	r.files = opt.FS
+	r.Size = size
+	r.rdr = fancy.SecReader(ra, size)
+	r.blocks = newBlockCache(ra, size)
+	r.Permissions = PermAll
+
+	fail := func(err error) (*PdfReaderT, error) {
+		if closer != nil {
+			closer.Close()
+		}
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = fn
+		}
+		return nil, err
 	}
 
-	r.Size = r.rdr.Size()
-
 	v := make([]byte, 16)
 	r.rdr.ReadAt(v, 0)
 
 	if v[0] != '%' || v[1] != 'P' || v[2] != 'D' || v[3] != 'F' {
-		util.Log(string(v), "not a PDF")
-		r.rdr.Close()
-		return nil
+		hdr := findHeader(r.rdr)
+		if !opt.Repair || hdr < 0 {
+			util.Log(string(v), "not a PDF")
+			return fail(parseError(fn, 0, ErrNotPDF))
+		}
+		r.warn("skipped %d bytes before the header", hdr)
+		r.Size -= int64(hdr)
+		r.ra = io.NewSectionReader(ra, int64(hdr), r.Size)
+		r.rdr = fancy.SecReader(r.ra, r.Size)
+		r.blocks = newBlockCache(r.ra, r.Size)
+		r.rdr.ReadAt(v, 0)
 	}
 
-	x := bytes.IndexByte(v, '\r')
-	if x > 0 {
+	if x := bytes.IndexAny(v, "\r\n"); x > 0 {
 		v = v[:x]
 	}
 	r.Version = string(v)
 
-	if r.Startxref = xrefStart(r.rdr); r.Startxref == -1 {
-		util.Log(fn, "xrefStart error")
-		r.rdr.Close()
-		return nil
+	if r.Startxref = xrefStart(r.rdr); r.Startxref == -1 && opt.Repair {
+		if r.Startxref = xrefStartScan(r.rdr); r.Startxref != -1 {
+			r.warn("startxref not at the end of the file")
+		}
 	}
 
-	if r.Xref, r.Trailer = xrefReadTable(r.rdr, r.Startxref); r.Xref == nil {
-		r.Xref, rr, r.Trailer = xrefReadStream(r.rdr, r.Startxref)
+	if r.Startxref == -1 {
+		if !opt.Repair {
+			util.Log(fn, "xrefStart error")
+			return fail(parseError(fn, max64(0, r.Size-1024), ErrNoStartxref))
+		}
+		err = parseError(fn, -1, ErrNoStartxref)
+	} else {
+		var x *xrefT
+		if x, r.Trailer, err = xrefRead(r.rdr, r.Startxref); x != nil {
+			r.Xref, r.XrefGen, r.Compressed = x.offs, x.gen, x.c
+		}
+	}
+
+	var objstms []int
+	if opt.Repair && (r.Xref == nil || r.Trailer == nil || !r.checkXref()) {
+		var pe *ParseError
+		if errors.As(err, &pe) && pe.Offset >= 0 {
+			r.warn("%v at offset %d", pe.Err, pe.Offset)
+		} else if errors.As(err, &pe) {
+			r.warn("%v", pe.Err)
+		}
+		r.Xref, r.XrefGen, r.Trailer, objstms = r.scanFile()
+		r.Compressed = nil
 	}
 
 	if r.Xref == nil {
 		util.Log(fn, "xrefRead error")
-		r.rdr.Close()
-		return nil
+		return fail(err)
 	}
 
 	if r.Trailer == nil {
-		r.rdr.Seek(int64(xrefSkip(r.rdr, r.Startxref)), 0)
+		p := xrefSkip(r.rdr, r.Startxref)
+		r.rdr.Seek(int64(p), 0)
 
 		s, _ := ps.Token(r.rdr)
 		if string(s) != "trailer" {
 			util.Log(fn, "no trailer")
-			r.rdr.Close()
-			return nil
+			return fail(parseError(fn, int64(p), ErrNoTrailer))
 		}
 		s, _ = ps.Token(r.rdr)
 		if r.Trailer = Dictionary(s); r.Trailer == nil {
 			util.Log(fn, "no trailer dictionary")
-			r.rdr.Close()
-			return nil
+			return fail(parseError(fn, int64(p), ErrNoTrailer))
 		}
 	}
+	r.rcache = newCache(opt.CacheSize, _CACHE_SIZE)
+	r.dicache = newCache(opt.CacheSize, _CACHE_SIZE)
+	r.objstms = newCache(opt.ObjStmCache, _OBJSTM_SIZE)
 
-	r.rcache = make(map[string][]byte)
-	r.rncache = make(map[string]int)
-	r.dicache = make(map[string]DictionaryT)
-
-	if rr != nil {
-		curr := -1
-
-		var dic DictionaryT
-		var s []byte
-
-		for _, v := range rr {
-			o, i := v[0], v[1]
-
-			if o != curr {
-				curr = o
-
-				dic, s = r.DecodedStream(util.MakeRef(curr))
-
-				first := num(dic["/First"])
-				n := num(dic["/N"])
-
-				rdr := fancy.SliceReader(s)
-
-				p := tuple(rdr, n*2)
-
-				util.Log("Object-Stream", curr)
-				for i := 0; i < len(p); i += 2 {
-					oo := num(p[i+0])
-					offs := num(p[i+1])
-
-					util.Log(oo, first+offs)
-
-					rdr.Seek(int64(first+offs), 0)
-					s, _ := ps.Token(rdr)
-					util.Log(string(s))
-
-					ref := string(util.MakeRef(oo))
-					r.rcache[ref] = s
-					r.rncache[ref] = -1
-				}
-
-			}
-
-			util.Log(o, i)
+	if _, ok := r.Trailer["/Encrypt"]; ok {
+		if r.crypt, err = newCrypt(r, opt.Password); err != nil {
+			return fail(err)
 		}
 	}
 
+	if objstms != nil {
+		r.Compressed = r.scanObjectStreams(objstms)
+	}
+
 	r.PageMode = string(r.Dic(r.Trailer["/Root"])["/PageMode"])
+	r.rdr = nil
 
-	return r
+	return r, nil
 }
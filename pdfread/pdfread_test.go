@@ -0,0 +1,298 @@
+package pdfread
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func readFile(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// makePDF() builds a PDF file of the objects, by number, with a classic
+// xref table; /Size is added to the trailer.
+func makePDF(objs map[int]string, trailer string) []byte {
+	b := []byte("%PDF-1.4\n")
+	n := 0
+	for o := range objs {
+		n = max(n, o+1)
+	}
+	offs := make([]int, n)
+	for o := 1; o < n; o++ {
+		if s, ok := objs[o]; ok {
+			offs[o] = len(b)
+			b = append(b, fmt.Sprintf("%d 0 obj\n%s\nendobj\n", o, s)...)
+		}
+	}
+	x := len(b)
+	b = append(b, fmt.Sprintf("xref\n0 %d\n", n)...)
+	for o := 0; o < n; o++ {
+		if offs[o] > 0 {
+			b = append(b, fmt.Sprintf("%010d 00000 n \n", offs[o])...)
+		} else {
+			b = append(b, "0000000000 65535 f \n"...)
+		}
+	}
+	return append(b, fmt.Sprintf("trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", n, trailer, x)...)
+}
+
+func TestOpenErrors(t *testing.T) {
+	plain := readFile(t, "plain.pdf")
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"not a PDF", []byte("<html></html>\n"), ErrNotPDF},
+		{"empty", []byte{}, ErrNotPDF},
+		{"no startxref", bytes.Replace(plain, []byte("startxref"), []byte("startxrfe"), 1), ErrNoStartxref},
+		{"bad startxref", bytes.Replace(plain, []byte("startxref\n382"), []byte("startxref\n300"), 1), ErrBadXref},
+		{"no trailer", bytes.Replace(plain, []byte("trailer"), []byte("trialer"), 1), ErrNoTrailer},
+	}
+	for _, tt := range tests {
+		_, err := OpenBytes(tt.data)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.File != "<buffer>" {
+			t.Errorf("%s: %v is not a *ParseError for <buffer>", tt.name, err)
+		}
+		if pd := LoadBytes(tt.data); pd != nil {
+			t.Errorf("%s: LoadBytes() = %v, want nil", tt.name, pd)
+		}
+	}
+
+	if _, err := Open("testdata/missing.pdf"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v, want %v", err, os.ErrNotExist)
+	}
+	pd, err := Open("testdata/plain.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	if pd.Version != "%PDF-1.6" || len(pd.Pages()) != 1 {
+		t.Errorf("plain.pdf: version %q, %d pages", pd.Version, len(pd.Pages()))
+	}
+}
+
+// readerAt counts the ReadAt() calls and whether it was closed.
+type readerAt struct {
+	*bytes.Reader
+	reads  int
+	closed bool
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	r.reads++
+	return r.Reader.ReadAt(p, off)
+}
+
+func (r *readerAt) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestNewReader(t *testing.T) {
+	plain := readFile(t, "plain.pdf")
+	r := &readerAt{Reader: bytes.NewReader(plain)}
+	pd, err := NewReader(r, int64(len(plain)), "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pd.File != "plain" || pd.Size != int64(len(plain)) || r.reads == 0 {
+		t.Errorf("File %q, Size %d, %d reads", pd.File, pd.Size, r.reads)
+	}
+	if n := len(pd.Pages()); n != 1 {
+		t.Errorf("%d pages", n)
+	}
+	pd.Close()
+	if r.closed {
+		t.Errorf("pd.Close() closed the reader")
+	}
+
+	// the size limits the reader
+	if _, err := NewReader(r, int64(len(plain))-10, "short"); !errors.Is(err, ErrNoStartxref) {
+		t.Errorf("short: err = %v, want %v", err, ErrNoStartxref)
+	}
+}
+
+// TestHybrid reads files whose pages and /Info are only in the
+// /XRefStm section, in an object stream.
+func TestHybrid(t *testing.T) {
+	for _, file := range []string{"hybrid.pdf", "hybrid1.pdf"} {
+		pd, err := Open("testdata/" + file)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+		if n := len(pd.Pages()); n != 1 {
+			t.Errorf("%s: %d pages", file, n)
+		}
+		if title := pd.Obj(pd.Dic(pd.Trailer["/Info"])["/Title"]); string(title) != "(Hybrid file)" {
+			t.Errorf("%s: title %s", file, title)
+		}
+		if c, ok := pd.Compressed[2]; !ok || c[0] != 7 {
+			t.Errorf("%s: object 2 is not in object stream 7: %v", file, pd.Compressed)
+		}
+		pd.Close()
+	}
+
+	// a reader ignoring /XRefStm sees objects 2 and 5 as free
+	data := bytes.Replace(readFile(t, "hybrid.pdf"), []byte("/XRefStm"), []byte("/XRefSxx"), 1)
+	pd, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(pd.Pages()); n != 0 {
+		t.Errorf("without /XRefStm: %d pages", n)
+	}
+}
+
+// TestXrefStreams reads an xref stream with /Index [5 2 8 1] updating
+// one with /Index [0 8] through /Prev.
+func TestXrefStreams(t *testing.T) {
+	pd, err := Open("testdata/inc.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	for o := 1; o <= 8; o++ {
+		_, ok := pd.Xref[o]
+		if ok != (o != 6) {
+			t.Errorf("object %d in xref: %v", o, ok)
+		}
+	}
+	if title := pd.Obj(pd.Dic(pd.Trailer["/Info"])["/Title"]); string(title) != "(New title)" {
+		t.Errorf("title %s", title)
+	}
+	if v := pd.Obj([]byte("6 0 R")); len(v) != 0 {
+		t.Errorf("freed object 6 = %s", v)
+	}
+	if _, ok := pd.Trailer["/Prev"]; !ok || pd.Num(pd.Trailer["/Size"]) != 9 {
+		t.Errorf("trailer of the first xref stream %v", pd.Trailer)
+	}
+	if n := len(pd.Pages()); n != 1 {
+		t.Errorf("%d pages", n)
+	}
+}
+
+// TestGenerations reads gen.pdf, whose update replaces object 6 with
+// generation 1; object 4 is free with generation 1.
+func TestGenerations(t *testing.T) {
+	pd, err := Open("testdata/gen.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	info := pd.Dic(pd.Trailer["/Info"])
+	if title := pd.Obj(info["/Title"]); string(title) != "(New title)" {
+		t.Errorf("6 1 R = %s", title)
+	}
+	if subject := pd.Obj(info["/Subject"]); len(subject) != 0 {
+		t.Errorf("6 0 R = %s", subject)
+	}
+	if g := pd.XrefGen[6]; g != 1 || string(pd.ref(6)) != "6 1 R" {
+		t.Errorf("object 6 has generation %d", g)
+	}
+	if v := pd.Obj([]byte("4 1 R")); len(v) != 0 {
+		t.Errorf("free object 4 = %s", v)
+	}
+	if v := pd.Obj([]byte("5 1 R")); len(v) != 0 {
+		t.Errorf("5 1 R = %s", v)
+	}
+	if v, ok := pd.Value(info["/Title"]).(String); !ok || string(v) != "New title" {
+		t.Errorf("Value(6 1 R) = %#v", pd.Value(info["/Title"]))
+	}
+}
+
+// TestStreamReader reads the streams of filters.pdf both with
+// pd.StreamReader() and pd.DecodedStream().
+func TestStreamReader(t *testing.T) {
+	pd, err := Open("testdata/filters.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	for o := 3; o <= 6; o++ {
+		ref := []byte(fmt.Sprintf("%d 0 R", o))
+		_, r, err := pd.StreamReader(ref)
+		if err != nil {
+			t.Errorf("%s: %v", ref, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if _, want := pd.DecodedStream(ref); err != nil || len(got) == 0 || !bytes.Equal(got, want) {
+			t.Errorf("%s: read %d bytes, %v; want %d bytes", ref, len(got), err, len(want))
+		}
+	}
+	if _, _, err := pd.StreamReader([]byte("1 0 R")); !errors.Is(err, ErrNotStream) {
+		t.Errorf("1 0 R: err = %v, want %v", err, ErrNotStream)
+	}
+
+	data := bytes.Replace(readFile(t, "filters.pdf"), []byte("/RunLengthDecode"), []byte("/RunLengthDecodX"), 1)
+	pd, err = OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := pd.StreamReader([]byte("4 0 R")); !errors.Is(err, ErrUnsupportedFilter) {
+		t.Errorf("unknown filter: err = %v, want %v", err, ErrUnsupportedFilter)
+	}
+	pd.Close()
+}
+
+// TestLengths reads the streams of lengths.pdf, whose /Length is right
+// (object 3), too short, too long, an indirect 13 (object 6), a missing
+// object and absent; object 9 is compressed and object 20 has no
+// endstream.
+func TestLengths(t *testing.T) {
+	pd, err := Open("testdata/lengths.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	tests := []struct {
+		o    int
+		want string
+		warn string
+	}{
+		{3, "plain data", ""},
+		{4, "too short data", "/Length 9, endstream after 14 bytes"},
+		{5, "too long data", "/Length 53, endstream after 13 bytes"},
+		{6, "indirect good", ""},
+		{7, "indirect missing", "missing /Length, endstream after 16 bytes"},
+		{8, "no length at all", "missing /Length, endstream after 16 bytes"},
+		{9, strings.Repeat("hello compressed world ", 20), "endstream after"},
+		{20, "unterminated", "/Length 1000, no endstream found"},
+	}
+	for _, tt := range tests {
+		before := len(pd.Warnings())
+		ref := []byte(fmt.Sprintf("%d 0 R", tt.o))
+		_, data := pd.DecodedStream(ref)
+		if tt.o == 20 {
+			data = data[:min(len(data), len(tt.want))]
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: %q, want %q", ref, data, tt.want)
+		}
+		w := pd.Warnings()[before:]
+		if tt.warn == "" && len(w) != 0 || tt.warn != "" && (len(w) != 1 || !strings.Contains(w[0], tt.warn)) {
+			t.Errorf("%s: warnings %q, want %q", ref, w, tt.warn)
+		}
+		// the length found is kept
+		pd.DecodedStream(ref)
+		if n := len(pd.Warnings()); n != before+len(w) {
+			t.Errorf("%s: %d warnings after reading it again", ref, n-before)
+		}
+	}
+}
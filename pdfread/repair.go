@@ -0,0 +1,242 @@
+package pdfread
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/raff/pdfreader/fancy"
+	"github.com/raff/pdfreader/ps"
+	"github.com/raff/pdfreader/util"
+)
+
+const (
+	_SCAN_CHUNK   = 1 << 20
+	_SCAN_OVERLAP = 64
+)
+
+var (
+	startxrefLoose = regexp.MustCompile(`startxref[\x00\t\n\f\r ]+([0-9]+)`)
+	objHeader      = regexp.MustCompile(`(?:^|[\x00\t\n\f\r >\])}])([0-9]{1,10})[\x00\t\n\f\r ]+([0-9]{1,5})[\x00\t\n\f\r ]+obj\b`)
+	trailerHeader  = regexp.MustCompile(`trailer[\x00\t\n\f\r ]*<<`)
+)
+
+// pd.warn() records a problem found in the file.
+func (pd *PdfReaderT) warn(f string, args ...interface{}) {
+	w := fmt.Sprintf(f, args...)
+	util.Log(pd.File, w)
+	pd.mu.Lock()
+	pd.warnings = append(pd.warnings, w)
+	pd.mu.Unlock()
+}
+
+// pd.Warnings() returns the problems found in the file so far. Reading
+// streams can add more, see Options.Repair.
+func (pd *PdfReaderT) Warnings() []string {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	return append([]string(nil), pd.warnings...)
+}
+
+// findHeader() looks for the %PDF- header in the first 1024 bytes.
+func findHeader(f fancy.Reader) int {
+	b := make([]byte, min(1024, int(f.Size())))
+	f.ReadAt(b, 0)
+	return bytes.Index(b, []byte("%PDF-"))
+}
+
+// scanChunks() calls fn for all matches of re in the file, in file order.
+// The positions passed are the submatch indexes, shifted to file offsets.
+func scanChunks(f fancy.Reader, re *regexp.Regexp, fn func(m []int, b []byte, base int64)) {
+	size := f.Size()
+	b := make([]byte, _SCAN_CHUNK)
+	for base := int64(0); base < size; base += _SCAN_CHUNK - _SCAN_OVERLAP {
+		// fancy.Reader can't read beyond the end of the file
+		n, _ := f.ReadAt(b[:min(len(b), int(size-base))], base)
+		if n <= 0 {
+			break
+		}
+		last := base+int64(n) >= size
+		for _, m := range re.FindAllSubmatchIndex(b[:n], -1) {
+			// matches starting in the overlap are found again in the next chunk
+			if !last && m[0] >= _SCAN_CHUNK-_SCAN_OVERLAP {
+				continue
+			}
+			// a match anchored at the start of a chunk may be a cut token
+			if base > 0 && len(m) > 2 && m[0] == 0 && m[2] == 0 {
+				continue
+			}
+			fn(m, b[:n], base)
+		}
+		if last {
+			break
+		}
+	}
+}
+
+// xrefStartScan() looks for the last startxref anywhere in the file.
+func xrefStartScan(f fancy.Reader) int {
+	r := -1
+	scanChunks(f, startxrefLoose, func(m []int, b []byte, base int64) {
+		r = num(b[m[2]:m[3]])
+	})
+	return r
+}
+
+// objectAt() checks for the header of object o at position p.
+func objectAt(f fancy.Reader, p, o int) bool {
+	if p < 0 || int64(p) >= f.Size() {
+		return false
+	}
+	f.Seek(int64(p), 0)
+	m := tuple(f, 3)
+	return len(m[0]) > 0 && m[0][0] >= '0' && m[0][0] <= '9' &&
+		num(m[0]) == o && string(m[2]) == "obj"
+}
+
+// pd.endstreamAt() checks for the endstream keyword at position p, after
+// an optional EOL.
+func (pd *PdfReaderT) endstreamAt(p int64) bool {
+	if p < 0 || p >= pd.Size {
+		return false
+	}
+	b := make([]byte, min(16, int(pd.Size-p)))
+	n, _ := pd.ra.ReadAt(b, p)
+	b = bytes.TrimLeft(b[:n], "\x00\t\n\f\r ")
+	return bytes.HasPrefix(b, []byte("endstream"))
+}
+
+// pd.findEndstream() returns the length of the stream data starting at p:
+// the bytes up to the next endstream keyword, without the EOL before it.
+// It's -1 if there is no endstream.
+func (pd *PdfReaderT) findEndstream(p int64) int64 {
+	kw := []byte("endstream")
+	b := make([]byte, _SCAN_CHUNK)
+	for base := p; base < pd.Size; base += int64(len(b) - len(kw)) {
+		n, _ := pd.ra.ReadAt(b, base)
+		if n <= 0 {
+			break
+		}
+		if i := bytes.Index(b[:n], kw); i >= 0 {
+			e := base + int64(i)
+			// the EOL isn't part of the data
+			eol := make([]byte, 2)
+			if e-p >= 2 {
+				pd.ra.ReadAt(eol, e-2)
+			} else if e-p == 1 {
+				pd.ra.ReadAt(eol[1:], e-1)
+			}
+			switch {
+			case eol[0] == '\r' && eol[1] == '\n':
+				e -= 2
+			case eol[1] == '\n' || eol[1] == '\r':
+				e--
+			}
+			return e - p
+		}
+		if base+int64(n) >= pd.Size {
+			break
+		}
+	}
+	return -1
+}
+
+// pd.checkXref() verifies that all xref entries point to object headers.
+func (pd *PdfReaderT) checkXref() bool {
+	bad := 0
+	for o, p := range pd.Xref {
+		if !objectAt(pd.rdr, p, o) {
+			bad++
+		}
+	}
+	if bad > 0 {
+		pd.warn("%d of %d xref entries point to wrong offsets", bad, len(pd.Xref))
+	}
+	return bad == 0
+}
+
+// scanFile() rebuilds xref and trailer from the object headers and the
+// trailer dictionaries found in the file. Later definitions win, as for
+// incremental updates. It also returns the object streams found.
+func (pd *PdfReaderT) scanFile() (xref, gen map[int]int, trailer DictionaryT, objstms []int) {
+	f := pd.rdr
+	xref = make(map[int]int)
+	gen = make(map[int]int)
+	trailer = make(DictionaryT)
+
+	scanChunks(f, objHeader, func(m []int, b []byte, base int64) {
+		o := num(b[m[2]:m[3]])
+		xref[o] = int(base) + m[2]
+		gen[o] = num(b[m[4]:m[5]])
+	})
+
+	merge := func(d DictionaryT) {
+		for k, v := range d {
+			trailer[k] = v
+		}
+	}
+
+	var xrefstms []int
+	catalog := -1
+	objs := make([]int, 0, len(xref))
+	for o := range xref {
+		objs = append(objs, o)
+	}
+	sort.Slice(objs, func(i, j int) bool { return xref[objs[i]] < xref[objs[j]] })
+	for _, o := range objs {
+		f.Seek(int64(xref[o]), 0)
+		tuple(f, 3)
+		s, _ := ps.Token(f)
+		switch string(Dictionary(s)["/Type"]) {
+		case "/Catalog":
+			catalog = o
+		case "/ObjStm":
+			objstms = append(objstms, o)
+		case "/XRef":
+			xrefstms = append(xrefstms, xref[o])
+			merge(Dictionary(s))
+		}
+	}
+
+	scanChunks(f, trailerHeader, func(m []int, b []byte, base int64) {
+		f.Seek(base+int64(m[1]-2), 0)
+		s, _ := ps.Token(f)
+		merge(Dictionary(s))
+	})
+
+	for _, k := range []string{"/Prev", "/XRefStm", "/Index", "/W", "/Filter",
+		"/DecodeParms", "/Length", "/Type"} {
+		delete(trailer, k)
+	}
+	if _, ok := trailer["/Root"]; !ok && catalog >= 0 {
+		trailer["/Root"] = []byte(fmt.Sprintf("%d %d R", catalog, gen[catalog]))
+	}
+	if len(trailer) == 0 {
+		trailer = nil
+	}
+
+	pd.warn("rebuilt xref from %d objects, %d object streams, %d xref streams",
+		len(xref), len(objstms), len(xrefstms))
+	return
+}
+
+// pd.scanObjectStreams() locates the compressed objects in the object
+// streams found by pd.scanFile(), skipping the ones also found uncompressed.
+func (pd *PdfReaderT) scanObjectStreams(objstms []int) map[int][2]int {
+	where := make(map[int][2]int)
+	for _, o := range objstms {
+		s := pd.objStm(o)
+		if s == nil {
+			continue
+		}
+		for i := 0; i+1 < len(s.index); i += 2 {
+			if oo := num(s.index[i]); len(s.index[i]) > 0 {
+				if _, ok := pd.Xref[oo]; !ok {
+					where[oo] = [2]int{o, i / 2}
+				}
+			}
+		}
+	}
+	return where
+}
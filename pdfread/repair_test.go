@@ -0,0 +1,66 @@
+package pdfread
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/raff/pdfreader/ps"
+)
+
+// TestRepair opens damaged copies of plain.pdf with and without
+// Options.Repair.
+func TestRepair(t *testing.T) {
+	plain := readFile(t, "plain.pdf")
+	x := bytes.Index(plain, []byte("\nxref")) + 1
+	tests := []struct {
+		name string
+		data []byte
+		err  error // without Options.Repair
+	}{
+		{"BOM", append([]byte("\xef\xbb\xbf"), plain...), ErrNotPDF},
+		{"HTTP headers", append([]byte("HTTP/1.1 200 OK\r\nContent-Type: application/pdf\r\n\r\n"), plain...), ErrNotPDF},
+		{"shifted objects", bytes.Replace(plain, []byte("1 0 obj"), []byte("%junk\n1 0 obj"), 1), ErrBadXref},
+		{"no startxref", bytes.Replace(plain, []byte("startxref"), []byte("startxrfe"), 1), ErrNoStartxref},
+		{"truncated", plain[:x], ErrNoStartxref},
+	}
+	for _, tt := range tests {
+		if _, err := OpenBytes(tt.data); !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		pd, err := NewReaderOptions(bytes.NewReader(tt.data), int64(len(tt.data)), tt.name, &Options{Repair: true})
+		if err != nil {
+			t.Errorf("%s: repair: %v", tt.name, err)
+			continue
+		}
+		if len(pd.Warnings()) == 0 {
+			t.Errorf("%s: no warnings", tt.name)
+		}
+		if n := len(pd.Pages()); n != 1 {
+			t.Errorf("%s: %d pages", tt.name, n)
+		}
+		if tt.name != "truncated" { // no trailer, no /Info
+			if title := ps.String(pd.Obj(pd.Dic(pd.Trailer["/Info"])["/Title"])); string(title) != "Hello (World)" {
+				t.Errorf("%s: title %q", tt.name, title)
+			}
+		}
+		_, data := pd.DecodedStream(pd.Dic(pd.Pages()[0])["/Contents"])
+		if !bytes.Contains(data, []byte("(Hello) Tj")) {
+			t.Errorf("%s: contents %q", tt.name, data)
+		}
+		pd.Close()
+	}
+
+	pd, err := NewReaderOptions(bytes.NewReader(plain), int64(len(plain)), "plain", &Options{Repair: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := pd.Warnings(); len(w) != 0 {
+		t.Errorf("warnings for an intact file: %q", w)
+	}
+	pd.Close()
+
+	if _, err := NewReaderOptions(bytes.NewReader([]byte("no PDF")), 6, "text", &Options{Repair: true}); !errors.Is(err, ErrNotPDF) {
+		t.Errorf("text: err = %v, want %v", err, ErrNotPDF)
+	}
+}
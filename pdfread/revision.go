@@ -0,0 +1,186 @@
+package pdfread
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/raff/pdfreader/fancy"
+)
+
+// Revision is one revision of a PDF file: the original file or one of its
+// incremental updates.
+type Revision struct {
+	Start, End int64       // byte range of the revision in the file
+	Xref       int         // position of the revision's xref
+	Trailer    DictionaryT // trailer dictionary of the revision
+	Added      []int       // objects added by the revision
+	Changed    []int       // objects replaced by the revision
+	Freed      []int       // objects deleted by the revision
+	base, xref *xrefT      // xref before and after the revision
+}
+
+// pd.Revisions() returns the revisions of the file, oldest first. The two
+// xref sections of a linearized file are one revision.
+func (pd *PdfReaderT) Revisions() ([]Revision, error) {
+	pd.mu.Lock()
+	cached := pd.revisions
+	pd.mu.Unlock()
+	if cached == nil {
+		r, err := pd.readRevisions()
+		if err != nil {
+			return nil, err
+		}
+		pd.mu.Lock()
+		pd.revisions = r
+		pd.mu.Unlock()
+		cached = r
+	}
+	return append([]Revision(nil), cached...), nil
+}
+
+// pd.readRevisions() reads the xref chain for pd.Revisions().
+func (pd *PdfReaderT) readRevisions() ([]Revision, error) {
+	f := pd.at(0)
+	secs, err := xrefChain(f, pd.Startxref)
+	if err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = pd.File
+		}
+		return nil, err
+	}
+
+	var r []Revision
+	x := newXref()
+	for b := len(secs) - 1; b >= 0; b-- {
+		sec := secs[b]
+		base := x
+		x = x.clone()
+		if err := xrefApply(f, sec, x); err != nil {
+			return nil, parseError(pd.File, int64(sec.pos), ErrBadXref)
+		}
+		end := revisionEnd(f, sec)
+		if n := len(r); n > 0 && end <= r[n-1].End {
+			// the first page xref of a linearized file
+			r[n-1].Xref = sec.pos
+			r[n-1].Trailer = sec.trailer
+			r[n-1].xref = x
+			continue
+		}
+		start := int64(0)
+		if n := len(r); n > 0 {
+			start = r[n-1].End
+		}
+		r = append(r, Revision{Start: start, End: end, Xref: sec.pos,
+			Trailer: sec.trailer, base: base, xref: x})
+	}
+	for i := range r {
+		r[i].diff()
+	}
+	return r, nil
+}
+
+// rev.diff() lists the objects added, changed and freed by the revision.
+func (rev *Revision) diff() {
+	type loc struct{ p, g, s, i int }
+	where := func(x *xrefT, o int) (loc, bool) {
+		if p, ok := x.offs[o]; ok {
+			return loc{p, x.gen[o], -1, -1}, true
+		}
+		if c, ok := x.c[o]; ok {
+			return loc{-1, 0, c[0], c[1]}, true
+		}
+		return loc{}, false
+	}
+	objs := make(map[int]bool)
+	for _, x := range []*xrefT{rev.base, rev.xref} {
+		for o := range x.offs {
+			objs[o] = true
+		}
+		for o := range x.c {
+			objs[o] = true
+		}
+	}
+	for o := range objs {
+		old, before := where(rev.base, o)
+		now, after := where(rev.xref, o)
+		switch {
+		case !before:
+			rev.Added = append(rev.Added, o)
+		case !after:
+			rev.Freed = append(rev.Freed, o)
+		case old != now:
+			rev.Changed = append(rev.Changed, o)
+		}
+	}
+	sort.Ints(rev.Added)
+	sort.Ints(rev.Changed)
+	sort.Ints(rev.Freed)
+}
+
+// revisionEnd() returns the end of the revision with the xref section sec:
+// the position after the %%EOF following it.
+func revisionEnd(f fancy.Reader, sec xrefSectionT) int64 {
+	p := int64(sec.pos)
+	if sec.stream {
+		p += int64(num(sec.trailer["/Length"])) // don't look into the data
+	}
+	b := make([]byte, 4096)
+	for p < f.Size() {
+		// fancy.Reader can't read beyond the end of the file
+		n, _ := f.ReadAt(b[:min(len(b), int(f.Size()-p))], p)
+		if i := bytes.Index(b[:n], []byte("%%EOF")); i >= 0 {
+			p += int64(i + 5)
+			n, _ = f.ReadAt(b[:min(2, int(f.Size()-p))], p)
+			if n > 0 && b[0] == '\r' {
+				p++
+				b[0], n = b[1], n-1
+			}
+			if n > 0 && b[0] == '\n' {
+				p++
+			}
+			return p
+		}
+		if n < 5 {
+			break
+		}
+		p += int64(n - 4)
+	}
+	return f.Size()
+}
+
+// pd.Revision() returns a reader for the file as it was after revision n,
+// see pd.Revisions(). The reader shares the file with pd, so it can't be
+// used after pd.Close() and must not be closed itself.
+func (pd *PdfReaderT) Revision(n int) (*PdfReaderT, error) {
+	revs, err := pd.Revisions()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= len(revs) {
+		return nil, parseError(pd.File, -1, ErrNoRevision)
+	}
+	rev := revs[n]
+
+	r := new(PdfReaderT)
+	r.File = pd.File
+	r.Size = rev.End
+	r.Version = pd.Version
+	r.ra = pd.ra
+	r.blocks = pd.blocks
+	r.Startxref = rev.Xref
+	r.Xref = rev.xref.offs
+	r.XrefGen = rev.xref.gen
+	r.Compressed = rev.xref.c
+	r.Trailer = rev.Trailer
+	r.Encrypted = pd.Encrypted
+	r.Permissions = pd.Permissions
+	r.crypt = pd.crypt
+	r.files = pd.files
+	r.rcache = newCache(pd.rcache.max, _CACHE_SIZE)
+	r.dicache = newCache(pd.dicache.max, _CACHE_SIZE)
+	r.objstms = newCache(pd.objstms.max, _OBJSTM_SIZE)
+	r.PageMode = string(r.Dic(r.Trailer["/Root"])["/PageMode"])
+	return r, nil
+}
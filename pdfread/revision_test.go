@@ -0,0 +1,64 @@
+package pdfread
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestRevisions reads inc.pdf, whose update replaces the /Info dictionary
+// 5, frees object 6 and adds the xref stream 8.
+func TestRevisions(t *testing.T) {
+	pd, err := Open("testdata/inc.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pd.Close()
+	revs, err := pd.Revisions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("%d revisions", len(revs))
+	}
+	tests := []struct {
+		added, changed, freed []int
+		title                 string
+	}{
+		{[]int{1, 2, 3, 4, 5, 6, 7}, nil, nil, "(Old title)"},
+		{[]int{8}, []int{5}, []int{6}, "(New title)"},
+	}
+	for i, tt := range tests {
+		rev := revs[i]
+		if !reflect.DeepEqual(rev.Added, tt.added) || !reflect.DeepEqual(rev.Changed, tt.changed) || !reflect.DeepEqual(rev.Freed, tt.freed) {
+			t.Errorf("revision %d: added %v, changed %v, freed %v", i, rev.Added, rev.Changed, rev.Freed)
+		}
+		if i > 0 && rev.Start != revs[i-1].End || rev.End <= rev.Start {
+			t.Errorf("revision %d: bytes %d-%d", i, rev.Start, rev.End)
+		}
+		r, err := pd.Revision(i)
+		if err != nil {
+			t.Errorf("revision %d: %v", i, err)
+			continue
+		}
+		if title := r.Obj(r.Dic(r.Trailer["/Info"])["/Title"]); string(title) != tt.title {
+			t.Errorf("revision %d: title %s", i, title)
+		}
+		if n := len(r.Pages()); n != 1 {
+			t.Errorf("revision %d: %d pages", i, n)
+		}
+	}
+	if revs[1].End != pd.Size {
+		t.Errorf("last revision ends at %d of %d", revs[1].End, pd.Size)
+	}
+
+	revs[0].Added = nil
+	if again, _ := pd.Revisions(); again[0].Added == nil {
+		t.Errorf("Revisions() returned its cached slice")
+	}
+	for _, n := range []int{-1, 2} {
+		if _, err := pd.Revision(n); !errors.Is(err, ErrNoRevision) {
+			t.Errorf("Revision(%d): err = %v, want %v", n, err, ErrNoRevision)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package pdfread
+
+import (
+	"bytes"
+
+	"github.com/raff/pdfreader/ps"
+)
+
+// NameTree is a name tree, as /Dests, /EmbeddedFiles or /JavaScript of the
+// /Names dictionary: a map from strings to PDF objects, sorted by key. Keys
+// are the bytes of the strings, values are returned as PDF data, like the
+// entries of DictionaryT.
+type NameTree struct {
+	t treeT
+}
+
+// NumberTree is a number tree, as /PageLabels or /ParentTree: a map from
+// integers to PDF objects, sorted by key.
+type NumberTree struct {
+	t treeT
+}
+
+// treeT is the common part of name and number trees: intermediate nodes
+// with /Kids and /Limits, leaves with key-value pairs.
+type treeT struct {
+	pd   *PdfReaderT
+	root []byte
+	leaf string // "/Names" or "/Nums"
+}
+
+// pd.NameTree() returns the name tree with the root node at reference.
+func (pd *PdfReaderT) NameTree(reference []byte) *NameTree {
+	return &NameTree{treeT{pd, reference, "/Names"}}
+}
+
+// pd.NumberTree() returns the number tree with the root node at reference.
+func (pd *PdfReaderT) NumberTree(reference []byte) *NumberTree {
+	return &NumberTree{treeT{pd, reference, "/Nums"}}
+}
+
+// t.Get() looks up a key, false if it's not in the tree.
+func (t *NameTree) Get(key string) ([]byte, bool) {
+	k := []byte(key)
+	return t.t.get(func(s []byte) int {
+		return bytes.Compare(k, ps.String(s))
+	})
+}
+
+// t.Each() calls fn for the entries of the tree in order, until it returns
+// false.
+func (t *NameTree) Each(fn func(key string, value []byte) bool) {
+	t.t.each(func(k, v []byte) bool {
+		return fn(string(ps.String(k)), v)
+	})
+}
+
+// t.Get() looks up a key, false if it's not in the tree.
+func (t *NumberTree) Get(key int) ([]byte, bool) {
+	return t.t.get(func(s []byte) int {
+		return key - num(s)
+	})
+}
+
+// t.Each() calls fn for the entries of the tree in order, until it returns
+// false.
+func (t *NumberTree) Each(fn func(key int, value []byte) bool) {
+	t.t.each(func(k, v []byte) bool {
+		return fn(num(k), v)
+	})
+}
+
+// t.limits() returns the resolved /Limits of a node, false if they are
+// missing or malformed.
+func (t *treeT) limits(d DictionaryT) (lo, hi []byte, ok bool) {
+	lim := t.pd.Arr(d["/Limits"])
+	if len(lim) != 2 {
+		return nil, nil, false
+	}
+	return t.pd.Obj(lim[0]), t.pd.Obj(lim[1]), true
+}
+
+// t.get() looks for the value of the key for which cmp() is 0. cmp()
+// compares the key to a key of the tree. Nodes are found by a binary
+// search on /Limits; if the limits or the order of the keys are wrong the
+// nodes are searched one by one.
+func (t *treeT) get(cmp func(k []byte) int) ([]byte, bool) {
+	done := make(map[int]bool)
+	var get func(node []byte, depth int) ([]byte, bool)
+	get = func(node []byte, depth int) ([]byte, bool) {
+		if n, _ := refNums(node); n >= 0 {
+			if done[n] {
+				return nil, false
+			}
+			done[n] = true
+		}
+		if depth > MAX_TREE_DEPTH {
+			return nil, false
+		}
+		d := t.pd.Dic(node)
+
+		if kv := t.pd.Arr(d[t.leaf]); len(kv) > 0 {
+			lo, hi := 0, len(kv)/2
+			for lo < hi {
+				m := (lo + hi) / 2
+				switch c := cmp(t.pd.Obj(kv[2*m])); {
+				case c == 0:
+					return kv[2*m+1], true
+				case c < 0:
+					hi = m
+				default:
+					lo = m + 1
+				}
+			}
+			for i := 0; i+1 < len(kv); i += 2 {
+				if cmp(t.pd.Obj(kv[i])) == 0 {
+					return kv[i+1], true
+				}
+			}
+		}
+
+		kids := t.pd.Arr(d["/Kids"])
+		lo, hi := 0, len(kids)
+		for lo < hi {
+			m := (lo + hi) / 2
+			first, last, ok := t.limits(t.pd.Dic(kids[m]))
+			if !ok {
+				break
+			}
+			if cmp(first) < 0 {
+				hi = m
+			} else if cmp(last) > 0 {
+				lo = m + 1
+			} else {
+				if v, ok := get(kids[m], depth+1); ok {
+					return v, true
+				}
+				break
+			}
+		}
+		for _, kid := range kids {
+			// skip a kid only if the key is out of both limits, which
+			// may be swapped
+			if first, last, ok := t.limits(t.pd.Dic(kid)); ok {
+				if c0, c1 := cmp(first), cmp(last); c0 < 0 && c1 < 0 || c0 > 0 && c1 > 0 {
+					continue
+				}
+			}
+			if v, ok := get(kid, depth+1); ok {
+				return v, true
+			}
+		}
+		return nil, false
+	}
+	return get(t.root, 0)
+}
+
+// t.each() calls fn for the key-value pairs in tree order.
+func (t *treeT) each(fn func(k, v []byte) bool) {
+	done := make(map[int]bool)
+	var each func(node []byte, depth int) bool
+	each = func(node []byte, depth int) bool {
+		if n, _ := refNums(node); n >= 0 {
+			if done[n] {
+				return true
+			}
+			done[n] = true
+		}
+		if depth > MAX_TREE_DEPTH {
+			return true
+		}
+		d := t.pd.Dic(node)
+		kv := t.pd.Arr(d[t.leaf])
+		for i := 0; i+1 < len(kv); i += 2 {
+			if !fn(t.pd.Obj(kv[i]), kv[i+1]) {
+				return false
+			}
+		}
+		for _, kid := range t.pd.Arr(d["/Kids"]) {
+			if !each(kid, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	each(t.root, 0)
+}
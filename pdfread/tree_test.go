@@ -0,0 +1,99 @@
+package pdfread
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// treePDF() builds a file with a name tree of the keys k000, k002, ...
+// k118 (values vk000...) in three levels at 10 0 R, and a number tree at
+// 500 0 R with unsorted keys, wrong limits and a loop.
+func treePDF() []byte {
+	objs := map[int]string{
+		1:   "<< /Type /Catalog /Pages 2 0 R >>",
+		2:   "<< /Type /Pages /Kids [] /Count 0 >>",
+		500: "<< /Kids [501 0 R 502 0 R 503 0 R] >>",
+		501: "<< /Limits [0 9] /Nums [0 (zero) 5 (five) 9 (nine)] >>",
+		502: "<< /Limits [50 10] /Nums [30 (thirty) 12 (twelve) 20 (twenty)] >>",
+		503: "<< /Limits [100 200] /Kids [500 0 R 504 0 R] >>",
+		504: "<< /Nums [150 (x150)] >>",
+	}
+	var mids []string
+	for m := 0; m < 4; m++ {
+		var leaves []string
+		for l := 0; l < 3; l++ {
+			o := 100 + m*3 + l
+			var kv []string
+			for i := 0; i < 5; i++ {
+				k := fmt.Sprintf("k%03d", ((m*3+l)*5+i)*2)
+				kv = append(kv, fmt.Sprintf("(%s) (v%s)", k, k))
+			}
+			objs[o] = fmt.Sprintf("<< /Limits [%s %s] /Names [%s] >>",
+				kv[0][:6], kv[4][:6], strings.Join(kv, " "))
+			leaves = append(leaves, fmt.Sprintf("%d 0 R", o))
+		}
+		objs[20+m] = fmt.Sprintf("<< /Limits [(k%03d) (k%03d)] /Kids [%s] >>", m*30, m*30+28, strings.Join(leaves, " "))
+		mids = append(mids, fmt.Sprintf("%d 0 R", 20+m))
+	}
+	objs[10] = "<< /Kids [" + strings.Join(mids, " ") + "] >>"
+	return makePDF(objs, "/Root 1 0 R")
+}
+
+func TestNameTree(t *testing.T) {
+	pd, err := OpenBytes(treePDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := pd.NameTree([]byte("10 0 R"))
+	for i := 0; i < 120; i++ {
+		k := fmt.Sprintf("k%03d", i)
+		v, ok := tree.Get(k)
+		if ok != (i%2 == 0) || ok && string(v) != "(v"+k+")" {
+			t.Errorf("Get(%s) = %s, %v", k, v, ok)
+		}
+	}
+	if _, ok := tree.Get("a"); ok {
+		t.Errorf("Get(a) found")
+	}
+
+	var keys []string
+	tree.Each(func(k string, v []byte) bool {
+		keys = append(keys, k)
+		return len(keys) < 20
+	})
+	if len(keys) != 20 || keys[0] != "k000" || keys[19] != "k038" {
+		t.Errorf("Each() stopped at %d keys: %q", len(keys), keys)
+	}
+}
+
+func TestNumberTree(t *testing.T) {
+	pd, err := OpenBytes(treePDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := pd.NumberTree([]byte("500 0 R"))
+	tests := []struct {
+		key  int
+		want string
+	}{
+		{0, "(zero)"}, {9, "(nine)"}, {12, "(twelve)"}, {20, "(twenty)"}, {30, "(thirty)"}, {150, "(x150)"},
+		{1, ""}, {40, ""}, {160, ""},
+	}
+	for _, tt := range tests {
+		v, ok := tree.Get(tt.key)
+		if ok != (tt.want != "") || string(v) != tt.want {
+			t.Errorf("Get(%d) = %s, %v", tt.key, v, ok)
+		}
+	}
+
+	var keys []int
+	tree.Each(func(k int, v []byte) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if want := []int{0, 5, 9, 30, 12, 20, 150}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Each() keys %v, want %v", keys, want)
+	}
+}
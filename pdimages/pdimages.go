@@ -14,6 +14,7 @@ import (
 	"log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/raff/pdfreader/fancy"
@@ -30,7 +31,7 @@ var (
 )
 
 func complain(err string) {
-	fmt.Printf("%susage: pdimages [--page #] foo.pdf\n", err)
+	fmt.Printf("%susage: pdimages [--page # | --label label] [--labels] foo.pdf\n", err)
 	os.Exit(1)
 }
 
@@ -210,13 +211,15 @@ func (t *TiffBuilder) WriteIFD(data []byte, next bool) {
 }
 
 func extract(pd *pdfread.PdfReaderT, page int, base string) (count, size int, err error) {
-	pg := pd.Pages()[page-1]
-	mbox := util.StringArray(pd.Arr(pd.Att("/MediaBox", pg)))
-	fmt.Println("Page", page)
-	fmt.Println("  MediaBox", mbox)
+	pg := pd.Page(page - 1)
+	if label := pd.PageLabel(page - 1); label != strconv.Itoa(page) {
+		fmt.Println("Page", page, label)
+	} else {
+		fmt.Println("Page", page)
+	}
+	fmt.Println("  MediaBox", pg.MediaBox)
 
-	resources := pd.Dic(pd.Att("/Resources", pg))
-	if xo := pd.Dic(resources["/XObject"]); xo != nil {
+	if xo := pd.Dic(pg.Resources["/XObject"]); xo != nil {
 		for name, ref := range xo {
 			dic, data := pd.Stream(ref)
 			printdic(dic, name, "  ")
@@ -357,11 +360,23 @@ func extract(pd *pdfread.PdfReaderT, page int, base string) (count, size int, er
 	return
 }
 
+// labelName() makes a page label usable in file names.
+func labelName(label string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, label)
+}
+
 func main() {
 	flag.BoolVar(&util.Debug, "debug", false, "print debug info")
 	flag.BoolVar(&dryrun, "n", false, "dry-run / don't extract images")
 	//sizes := flag.Bool("sizes", false, "print images info")
-	page := flag.Int("page", 0, "page to extract, all pages if missing")
+	page := flag.Int("page", 0, "page number (from 1, negative from the end) to extract, all pages if missing")
+	label := flag.String("label", "", "page label of the page to extract, instead of --page")
+	labels := flag.Bool("labels", false, "name the output of each page by page label instead of page number")
 
 	flag.Parse()
 
@@ -377,10 +392,17 @@ func main() {
 
 	npages := len(pd.Pages())
 
+	if *label != "" {
+		if *page != 0 {
+			complain("Use either --page or --label!\n\n")
+		}
+		if *page = pd.PageByLabel(*label) + 1; *page == 0 {
+			complain("Page label not found!\n\n")
+		}
+	}
 	if *page < 0 {
 		*page += npages + 1
 	}
-
 	if *page < 0 || *page > npages {
 		complain("Page out of range!\n\n")
 	}
@@ -394,8 +416,18 @@ func main() {
 	tsize := 0
 
 	if *page == 0 {
+		seen := map[string]bool{}
 		for p := 1; p <= npages; p++ {
 			pout := fmt.Sprintf("%s_%d", output, p)
+			if *labels {
+				// by page number for duplicate labels
+				name := labelName(pd.PageLabel(p - 1))
+				if name == "" || seen[name] {
+					name = strconv.Itoa(p)
+				}
+				seen[name] = true
+				pout = fmt.Sprintf("%s_%s", output, name)
+			}
 			if n, sz, err := extract(pd, p, pout); err != nil {
 				log.Println("error extracting images for page", p, err)
 				break
@@ -34,20 +34,65 @@ func main() {
 	flag.BoolVar(&pdutil.Debugobj, "dump", false, "dump object content")
 	flag.IntVar(&maxlevel, "levels", 5, "maximum number of levels")
 	displayref := flag.String("r", "", "display resource by reference")
+	password := flag.String("password", "", "password for encrypted files")
+	repair := flag.Bool("repair", false, "try to read damaged files")
 
 	flag.Parse()
 
 	for _, f := range flag.Args() {
 		fmt.Println("----", f, "--------------------")
 
-		pd := pdfread.Load(f)
-		if pd == nil {
-			fmt.Println("can't open input file")
+		pd, err := pdfread.OpenOptions(f, &pdfread.Options{Password: *password, Repair: *repair})
+		if err != nil {
+			fmt.Println("can't open input file:", err)
 			fmt.Println()
 			continue
 		}
 
 		fmt.Println(pd.Version)
+		for _, w := range pd.Warnings() {
+			fmt.Println("warning:", w)
+		}
+		if l, err := pd.Linearization(); l != nil {
+			fmt.Println("linearized")
+			for _, p := range l.Problems {
+				fmt.Println("linearization problem:", p)
+			}
+			if err != nil {
+				fmt.Println("linearization:", err)
+			}
+		}
+		if info := pd.Info(); info != nil {
+			for _, f := range [][2]string{{"Title", info.Title}, {"Author", info.Author},
+				{"Subject", info.Subject}, {"Keywords", info.Keywords},
+				{"Creator", info.Creator}, {"Producer", info.Producer}} {
+				if f[1] != "" {
+					fmt.Printf("%s: %s\n", f[0], f[1])
+				}
+			}
+			if !info.CreationDate.IsZero() {
+				fmt.Println("CreationDate:", info.CreationDate)
+			}
+			if !info.ModDate.IsZero() {
+				fmt.Println("ModDate:", info.ModDate)
+			}
+		}
+		if x, err := pd.XMP(); err != nil {
+			fmt.Println("XMP:", err)
+		} else if x != nil && x.Part > 0 {
+			fmt.Printf("PDF/A-%d%s\n", x.Part, strings.ToLower(x.Conformance))
+		}
+		fmt.Println("Pages:", len(pd.Pages()))
+		if _, ok := pd.Dic(pd.Trailer["/Root"])["/PageLabels"]; ok {
+			for i, l := range pd.PageLabels() {
+				fmt.Printf("Page %d: %s\n", i+1, l)
+			}
+		}
+		if form := pd.Form(); form != nil {
+			for _, f := range form.Fields() {
+				fmt.Printf("Field %s %s: %q\n", f.Name, f.Type, f.Value)
+			}
+		}
 		fmt.Println()
 
 		if *displayref != "" {
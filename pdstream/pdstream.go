@@ -9,22 +9,54 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/raff/pdfreader/pdfread"
 	"github.com/raff/pdfreader/util"
+	"io"
 	"os"
 )
 
 // The program takes a PDF file and an object reference of a stream.
 // The output are the decoded stream contents.
 //
+// The data of external streams (/F) is only read with -external, from
+// the directory of the PDF file.
+//
 // Example:
 //  ./pdstream.go foo.pdf "9 0 R"
+//  ./pdstream.go -external foo.pdf "9 0 R"
+
+var external = flag.Bool("external", false, "read external streams from the directory of the PDF file")
 
 func main() {
-	pd := pdfread.Load(os.Args[1])
-	_, d := pd.DecodedStream(util.Bytes(os.Args[2]))
-	fmt.Printf("%s", d)
+	flag.Parse()
+	if flag.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: pdstream [-external] foo.pdf \"9 0 R\"")
+		os.Exit(1)
+	}
+	pd, err := pdfread.OpenOptions(flag.Arg(0), &pdfread.Options{ExternalFiles: *external})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer pd.Close()
+	ref := util.Bytes(flag.Arg(1))
+	if name := pd.StreamFile(ref); name != "" {
+		if *external {
+			fmt.Fprintln(os.Stderr, "external stream:", name)
+		} else {
+			fmt.Fprintln(os.Stderr, "external stream:", name, "(use -external to read it)")
+		}
+	}
+	_, r, err := pd.StreamReader(ref)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		pd.Close()
+		os.Exit(1)
+	}
+	io.Copy(os.Stdout, r)
+	r.Close()
 
 	/*
 	   a := cmapi.Read(fancy.SliceReader(d));
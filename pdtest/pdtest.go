@@ -11,6 +11,13 @@ import (
 // The program takes a PDF file as argument and writes the MediaBoxes and
 // defined fonts of the pages.
 
+func printOutlines(outlines []pdfread.Outline, indent string) {
+	for _, p := range outlines {
+		fmt.Println(indent+"Page", p.Page+1, "-", p.Title)
+		printOutlines(p.Children, indent+"  ")
+	}
+}
+
 func main() {
 	pd := pdfread.Load(os.Args[1])
 	if pd != nil {
@@ -21,25 +28,24 @@ func main() {
 
 		if len(outlines) > 0 {
 			fmt.Println("Outlines:")
-			for _, p := range outlines {
-				fmt.Println("Page", p.Page+1, "-", p.Title)
-			}
-
+			printOutlines(outlines, "")
 			fmt.Println()
 		}
 
 		fmt.Println("Pages:")
-		pg := pd.Pages()
-
-		for k := range pg {
+		for k := range pd.Pages() {
+			page := pd.Page(k)
 			fmt.Printf("Page %d - MediaBox: %s\n",
-				k+1, pd.Att("/MediaBox", pg[k]))
-			fonts := pd.PageFonts(pg[k])
+				k+1, page.MediaBox)
+			fonts := pd.Dic(page.Resources["/Font"])
 			for l := range fonts {
 				fontname := pd.Dic(fonts[l])["/BaseFont"]
 				fmt.Printf("  %s = \"%s\"\n",
 					l, fontname[1:])
 			}
+			for _, a := range page.Annotations() {
+				fmt.Printf("  %s %s %q\n", a.Subtype, a.Rect, a.Contents)
+			}
 		}
 	}
 }
@@ -21,21 +21,18 @@ import (
 // The program takes a PDF file and converts a page to SVG.
 
 func complain(err string) {
-	fmt.Printf("%susage: pdtosvg [--html] [--page=n] foo.pdf >foo.svg\n", err)
+	fmt.Printf("%susage: pdtosvg [--html] [--page=n | --label=label] foo.pdf >foo.svg\n", err)
 	os.Exit(1)
 }
 
 func main() {
 	asHtml := flag.Bool("html", false, "output as html (true) or xml (false)")
 	debug := flag.Bool("debug", false, "debug mode")
-	page := flag.Int("page", 0, "page number")
+	page := flag.Int("page", 1, "page number (from 1)")
+	label := flag.String("label", "", "page label, instead of --page")
 
 	flag.Parse()
 
-	if *page < 0 {
-		complain("Bad page!\n\n")
-	}
-
 	if flag.NArg() != 1 {
 		complain("")
 	}
@@ -45,16 +42,22 @@ func main() {
 		complain("Could not load pdf file!\n\n")
 	}
 
+	if *label != "" {
+		if *page = pd.PageByLabel(*label) + 1; *page == 0 {
+			complain("Page label not found!\n\n")
+		}
+	}
+	if *page < 1 || *page > len(pd.Pages()) {
+		complain("Bad page!\n\n")
+	}
+
 	util.Debug = *debug
 
 	if *asHtml {
 		fmt.Println("<!DOCTYPE html><html><body>")
 	}
 
-	if *page < 0 {
-	} else {
-		os.Stdout.Write(svg.Page(pd, *page, *asHtml))
-	}
+	os.Stdout.Write(svg.Page(pd, *page-1, *asHtml))
 
 	if *asHtml {
 		fmt.Println("</body></html>")
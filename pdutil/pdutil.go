@@ -97,6 +97,11 @@ func (n printNested) printobj(w io.Writer, pd *pdfread.PdfReaderT, o []byte, ind
 		fmt.Fprintf(w, "%s]\n", indent)
 
 	case '<': // dictionary
+		if len(o) < 2 || o[1] != '<' { // hex string
+			fmt.Fprintf(w, "%s%s %q\n", indent, prefix, util.String(o))
+			break
+		}
+
 		d := pdfread.Dictionary(o)
 
 		fmt.Fprintf(w, "%s%s %s\n", indent, prefix, "{")
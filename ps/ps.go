@@ -142,10 +142,19 @@ again:
 
 func String(s []byte) []byte {
 	if s[0] == '<' {
-		if (len(s) % 2) == 1 { // odd length
-			s = append(s, '0') // they saved a full character here!
+		h := make([]byte, 0, len(s))
+		for _, c := range s[1:] {
+			if c == '>' {
+				break
+			}
+			if (c >= '0' && c <= '9') || (c >= 'A' && c <= 'F') || (c >= 'a' && c <= 'f') {
+				h = append(h, c)
+			}
+		}
+		if (len(h) % 2) == 1 { // odd length
+			h = append(h, '0') // they saved a full character here!
 		}
-		r, _ := hex.DecodeString(string(s[1 : len(s)-1]))
+		r, _ := hex.DecodeString(string(h))
 		return r
 	}
 	if s[0] != '(' {
@@ -49,7 +49,7 @@ func String(s []byte) string {
 	}
 
 	if s[0] == '<' { // this is a hex encoded string
-		if s[l-1] != ')' {
+		if s[l-1] != '>' {
 			Log("invalid hex string", string(s))
 			return ""
 		}